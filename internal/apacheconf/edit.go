@@ -0,0 +1,175 @@
+package apacheconf
+
+import (
+	"path/filepath"
+)
+
+// Walk visits every node depth first, descending into sections and into the
+// files of resolved includes. Returning false from fn stops the walk.
+func (f *File) Walk(fn func(n *Node) bool) {
+	walk(f.Root.Children, true, fn)
+}
+
+// walk visits the nodes and their children and, when includes is set, the
+// files of resolved includes.
+func walk(nodes []*Node, includes bool, fn func(n *Node) bool) bool {
+	for _, n := range nodes {
+		if !fn(n) {
+			return false
+		}
+		if !walk(n.Children, includes, fn) {
+			return false
+		}
+		if !includes {
+			continue
+		}
+		for _, included := range n.Included {
+			if !walk(included.Root.Children, includes, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Directives returns every directive with the name, in sections and included files too.
+func (f *File) Directives(name string) []*Node {
+	var found []*Node
+	f.Walk(func(n *Node) bool {
+		if n.Kind == Directive && n.Is(name) {
+			found = append(found, n)
+		}
+		return true
+	})
+	return found
+}
+
+// FindSection returns the first section with the name and, when given, the
+// arguments, wherever it is.
+func (f *File) FindSection(name string, args ...string) *Node {
+	var found *Node
+	f.Walk(func(n *Node) bool {
+		if n.Kind == Section && n.Is(name) && (len(args) == 0 || equalArgs(n.Args, args)) {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// ModuleLoaded reports whether a LoadModule directive loads the module (e.g. "ssl_module").
+func (f *File) ModuleLoaded(module string) bool {
+	for _, directive := range f.Directives("LoadModule") {
+		if len(directive.Args) > 0 && directive.Args[0] == module {
+			return true
+		}
+	}
+	return false
+}
+
+// Includes returns the patterns of the Include and IncludeOptional directives.
+func (f *File) Includes() []string {
+	var patterns []string
+	for _, name := range []string{"Include", "IncludeOptional"} {
+		for _, directive := range f.Directives(name) {
+			if len(directive.Args) > 0 {
+				patterns = append(patterns, directive.Args[0])
+			}
+		}
+	}
+	return patterns
+}
+
+// HasInclude reports whether the file includes the pattern.
+func (f *File) HasInclude(pattern string) bool {
+	for _, include := range f.Includes() {
+		if filepath.Clean(include) == filepath.Clean(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableModule makes sure the module is loaded: a commented-out LoadModule line
+// is uncommented in place, otherwise one is added after the last LoadModule
+// line. It reports whether the file changed. The includes must be resolved
+// first, or a module an included file loads (Debian's mods-enabled, Fedora's
+// conf.modules.d) is loaded a second time.
+func (f *File) EnableModule(module, file string) bool {
+	if f.ModuleLoaded(module) {
+		return false
+	}
+
+	var commented, last *Node
+	for _, n := range f.Root.Children {
+		if n.Is("LoadModule") {
+			last = n
+		}
+		if name, args, ok := n.CommentedDirective(); ok && commented == nil && name == "LoadModule" && len(args) > 0 && args[0] == module {
+			commented = n
+		}
+	}
+
+	switch {
+	case commented != nil:
+		commented.Uncomment()
+	case last != nil:
+		last.InsertAfter(NewDirective("LoadModule", module, file))
+	default:
+		f.Root.Insert(0, NewDirective("LoadModule", module, file))
+	}
+	return true
+}
+
+// AddInclude makes sure the file includes the pattern: a commented-out Include
+// line is uncommented in place, otherwise one is added at the end of the file.
+// It reports whether the file changed.
+func (f *File) AddInclude(pattern string) bool {
+	if f.HasInclude(pattern) {
+		return false
+	}
+
+	for _, n := range f.Root.Children {
+		name, args, ok := n.CommentedDirective()
+		if ok && (name == "Include" || name == "IncludeOptional") && len(args) > 0 && filepath.Clean(args[0]) == filepath.Clean(pattern) {
+			return n.Uncomment()
+		}
+	}
+
+	f.Root.Append(NewDirective("Include", pattern))
+	return true
+}
+
+// SetDirective sets a directive of the section, the top level of the file when
+// section is nil, replacing the arguments of an existing one or adding it at
+// the end of the section. It reports whether the file changed.
+func (f *File) SetDirective(section *Node, name string, args ...string) bool {
+	if section == nil {
+		section = f.Root
+	}
+
+	if directive := section.Find(name); directive != nil && directive.Kind == Directive {
+		if sameArgs(directive.Args, args) {
+			return false
+		}
+		directive.SetArgs(args...)
+		return true
+	}
+
+	section.Append(NewDirective(name, args...))
+	return true
+}
+
+// sameArgs reports whether the arguments are identical.
+func sameArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
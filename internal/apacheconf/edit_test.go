@@ -0,0 +1,141 @@
+package apacheconf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile creates the file and its directories.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnableModuleSeesIncludedFiles(t *testing.T) {
+	root := t.TempDir()
+
+	// Debian loads its modules from mods-enabled/*.load, relative to the
+	// directory of apache2.conf
+	main := filepath.Join(root, "etc/apache2/apache2.conf")
+	mainContent := "LoadModule mpm_event_module /usr/lib/apache2/modules/mod_mpm_event.so\nIncludeOptional mods-enabled/*.load\n"
+	writeFile(t, main, mainContent)
+	writeFile(t, filepath.Join(root, "etc/apache2/mods-enabled/ssl.load"), "LoadModule ssl_module /usr/lib/apache2/modules/mod_ssl.so\n")
+
+	conf, err := LoadResolved(main, root)
+	if err != nil {
+		t.Fatalf("LoadResolved: %v", err)
+	}
+
+	if conf.EnableModule("ssl_module", "/usr/lib/apache2/modules/mod_ssl.so") {
+		t.Errorf("EnableModule added ssl_module, which mods-enabled/ssl.load already loads")
+	}
+	if string(conf.Bytes()) != mainContent {
+		t.Errorf("apache2.conf changed:\n%s", conf.Bytes())
+	}
+
+	// A module no file loads is added to the main file only
+	if !conf.EnableModule("http2_module", "/usr/lib/apache2/modules/mod_http2.so") {
+		t.Fatalf("EnableModule did not add http2_module")
+	}
+	if !strings.Contains(string(conf.Bytes()), "LoadModule http2_module /usr/lib/apache2/modules/mod_http2.so\n") {
+		t.Errorf("apache2.conf does not load http2_module:\n%s", conf.Bytes())
+	}
+	if strings.Contains(string(conf.Bytes()), "ssl_module") {
+		t.Errorf("the included LoadModule line was written to apache2.conf:\n%s", conf.Bytes())
+	}
+}
+
+func TestEnableModuleUncommentsInPlace(t *testing.T) {
+	f, err := Parse("httpd.conf", []byte(httpdConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.EnableModule("ssl_module", "lib/httpd/modules/mod_ssl.so") {
+		t.Fatal("EnableModule did not enable ssl_module")
+	}
+	if !f.EnableModule("http2_module", "lib/httpd/modules/mod_http2.so") {
+		t.Fatal("EnableModule did not enable http2_module")
+	}
+	if f.EnableModule("authz_core_module", "lib/httpd/modules/mod_authz_core.so") {
+		t.Error("EnableModule changed the file for a module already loaded")
+	}
+
+	want := strings.Replace(httpdConf, "#LoadModule ssl_module", "LoadModule ssl_module", 1)
+	want = strings.Replace(want, "\t# LoadModule http2_module", "\tLoadModule http2_module", 1)
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("httpd.conf:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEnableModuleAddsAfterLastLoadModule(t *testing.T) {
+	f, err := Parse("httpd.conf", []byte(httpdConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.EnableModule("proxy_module", "lib/httpd/modules/mod_proxy.so")
+
+	want := strings.Replace(httpdConf, "    lib/httpd/modules/mod_rewrite.so\n", "    lib/httpd/modules/mod_rewrite.so\nLoadModule proxy_module lib/httpd/modules/mod_proxy.so\n", 1)
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("httpd.conf:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAddInclude(t *testing.T) {
+	f, err := Parse("httpd.conf", []byte(httpdConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commented out: uncommented in place
+	if !f.AddInclude("/opt/homebrew/etc/httpd/extra/httpd-ssl.conf") {
+		t.Fatal("AddInclude did not include httpd-ssl.conf")
+	}
+	// Missing: added at the end
+	if !f.AddInclude("/opt/homebrew/etc/httpd/extra/vhosts/*.conf") {
+		t.Fatal("AddInclude did not include the vhosts")
+	}
+	if f.AddInclude("/opt/homebrew/etc/httpd/extra/vhosts/*.conf") {
+		t.Error("AddInclude changed the file for a pattern already included")
+	}
+
+	want := strings.Replace(httpdConf, "#Include /opt", "Include /opt", 1) + "Include /opt/homebrew/etc/httpd/extra/vhosts/*.conf\n"
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("httpd.conf:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetDirectiveInSection(t *testing.T) {
+	f, err := Parse("httpd.conf", []byte(httpdConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section := f.FindSection("IfModule", "unixd_module")
+	if section == nil {
+		t.Fatal("no <IfModule unixd_module> section")
+	}
+	if f.SetDirective(section, "User", "_www") {
+		t.Error("SetDirective changed a directive to its own value")
+	}
+	if !f.SetDirective(section, "User", "dev") {
+		t.Error("SetDirective did not replace User")
+	}
+	if !f.SetDirective(section, "Umask", "002") {
+		t.Error("SetDirective did not add Umask")
+	}
+
+	want := strings.Replace(httpdConf, "    User _www\n    Group _www\n", "    User dev\n    Group _www\n    Umask 002\n", 1)
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("httpd.conf:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,245 @@
+package apacheconf
+
+import (
+	"strings"
+)
+
+// Kind is the kind of a line of configuration.
+type Kind int
+
+const (
+	Blank Kind = iota
+	Comment
+	Directive
+	Section
+)
+
+// Node is a line of an Apache configuration file: a blank line, a comment, a
+// directive or a section with its children. Nodes that are not edited are
+// written back exactly as they were read.
+type Node struct {
+	Kind     Kind
+	Name     string   // Directive or section name, as written
+	Args     []string // Arguments, without their quotes
+	Children []*Node  // The content of a section
+	Parent   *Node
+	Line     int     // Line number in the file, 0 for added nodes
+	Included []*File // The files an Include directive pulled in, see File.ResolveIncludes
+
+	indent  string
+	raw     []string // The original lines; nil once the node has been edited
+	closing []string // The original closing line of a section
+}
+
+// NewDirective returns a directive to insert into a file.
+func NewDirective(name string, args ...string) *Node {
+	return &Node{Kind: Directive, Name: name, Args: args}
+}
+
+// NewBlank returns a blank line to insert into a file.
+func NewBlank() *Node {
+	return &Node{Kind: Blank}
+}
+
+// NewSection returns an empty section to insert into a file.
+func NewSection(name string, args ...string) *Node {
+	return &Node{Kind: Section, Name: name, Args: args}
+}
+
+// Is reports whether the node is the named directive or section. Names are
+// case-insensitive, as they are for Apache.
+func (n *Node) Is(name string) bool {
+	return (n.Kind == Directive || n.Kind == Section) && strings.EqualFold(n.Name, name)
+}
+
+// CommentText returns the text of a comment, without the leading '#'.
+func (n *Node) CommentText() string {
+	if n.Kind != Comment {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.Join(n.raw, "\n")), "#"))
+}
+
+// CommentedDirective parses a commented-out directive such as "#LoadModule ssl_module ...".
+func (n *Node) CommentedDirective() (name string, args []string, ok bool) {
+	text := n.CommentText()
+	if text == "" || strings.HasPrefix(text, "<") {
+		return "", nil, false
+	}
+
+	fields, err := splitArgs(text)
+	if err != nil || len(fields) == 0 || !isName(fields[0]) {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// SetArgs replaces the arguments of a directive or section.
+func (n *Node) SetArgs(args ...string) {
+	n.Args = args
+	n.raw = nil
+}
+
+// Uncomment turns a commented-out directive back into a directive, keeping its
+// indentation. It reports false when the comment is not a directive.
+func (n *Node) Uncomment() bool {
+	name, args, ok := n.CommentedDirective()
+	if !ok {
+		return false
+	}
+
+	n.Kind = Directive
+	n.Name = name
+	n.Args = args
+	n.raw = nil
+	return true
+}
+
+// Find returns the first child directive or section with the name and, when
+// given, the arguments.
+func (n *Node) Find(name string, args ...string) *Node {
+	for _, child := range n.Children {
+		if child.Is(name) && (len(args) == 0 || equalArgs(child.Args, args)) {
+			return child
+		}
+	}
+	return nil
+}
+
+// Append adds nodes at the end of the section, with the indentation of its children.
+func (n *Node) Append(nodes ...*Node) {
+	n.Insert(len(n.Children), nodes...)
+}
+
+// Insert adds nodes to the section before the child at the index.
+func (n *Node) Insert(index int, nodes ...*Node) {
+	indent := n.childIndent()
+	for _, node := range nodes {
+		node.Parent = n
+		if node.raw == nil {
+			node.setIndent(indent)
+		}
+	}
+
+	n.Children = append(n.Children[:index], append(nodes, n.Children[index:]...)...)
+}
+
+// InsertAfter adds nodes to the parent of the reference node, right after it.
+func (n *Node) InsertAfter(nodes ...*Node) {
+	parent := n.Parent
+	for i, child := range parent.Children {
+		if child == n {
+			parent.Insert(i+1, nodes...)
+			return
+		}
+	}
+}
+
+// Remove takes the node out of its section.
+func (n *Node) Remove() {
+	parent := n.Parent
+	for i, child := range parent.Children {
+		if child == n {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// childIndent returns the indentation of the section's directives, guessed from
+// the existing ones.
+func (n *Node) childIndent() string {
+	for _, child := range n.Children {
+		if child.Kind == Directive || child.Kind == Section {
+			return child.indent
+		}
+	}
+	if n.Parent == nil {
+		return ""
+	}
+	return n.indent + "    "
+}
+
+// setIndent indents the node and, for new sections, its children.
+func (n *Node) setIndent(indent string) {
+	n.indent = indent
+	for _, child := range n.Children {
+		if child.raw == nil {
+			child.setIndent(indent + "    ")
+		}
+	}
+}
+
+// render writes the node, its children and its closing line.
+func (n *Node) render(b *strings.Builder) {
+	switch {
+	case n.raw != nil:
+		for _, line := range n.raw {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	case n.Kind == Section:
+		b.WriteString(n.indent + "<" + formatLine(n.Name, n.Args) + ">\n")
+	case n.Kind == Directive:
+		b.WriteString(n.indent + formatLine(n.Name, n.Args) + "\n")
+	default:
+		b.WriteString("\n")
+	}
+
+	if n.Kind != Section {
+		return
+	}
+
+	for _, child := range n.Children {
+		child.render(b)
+	}
+
+	if n.closing != nil {
+		for _, line := range n.closing {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	} else {
+		b.WriteString(n.indent + "</" + n.Name + ">\n")
+	}
+}
+
+// formatLine joins a name and its arguments, quoting the arguments that need it.
+func formatLine(name string, args []string) string {
+	parts := []string{name}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// equalArgs compares arguments, ignoring the case of the first one so that
+// section arguments such as module names match however they are written.
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && !(i == 0 && strings.EqualFold(a[i], b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// isName reports whether the word looks like a directive name.
+func isName(word string) bool {
+	if word == "" {
+		return false
+	}
+	for i, r := range word {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isLetter && (i == 0 || (r < '0' || r > '9') && r != '_') {
+			return false
+		}
+	}
+	return true
+}
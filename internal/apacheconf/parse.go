@@ -0,0 +1,251 @@
+package apacheconf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// maxIncludeDepth stops Include loops.
+const maxIncludeDepth = 16
+
+// File is a parsed Apache configuration file.
+type File struct {
+	Path string
+	Root *Node // A section without a name holding the top-level nodes
+
+	trailingNewline bool
+}
+
+// Load reads and parses a configuration file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+	return Parse(path, data)
+}
+
+// LoadResolved reads and parses a configuration file and the files it
+// includes, see ResolveIncludes. Edits still only change the file itself.
+func LoadResolved(path, root string) (*File, error) {
+	f, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.ResolveIncludes(root); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Parse parses the content of a configuration file. Lines ending with a
+// backslash continue on the next line and stay together in a single node.
+func Parse(path string, data []byte) (*File, error) {
+	text := string(data)
+	f := &File{
+		Path:            path,
+		Root:            &Node{Kind: Section},
+		trailingNewline: text == "" || strings.HasSuffix(text, "\n"),
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	current := f.Root
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		raw := []string{lines[i]}
+		logical := lines[i]
+		for strings.HasSuffix(logical, "\\") && i+1 < len(lines) {
+			i++
+			raw = append(raw, lines[i])
+			logical = strings.TrimSuffix(logical, "\\") + lines[i]
+		}
+
+		trimmed := strings.TrimSpace(logical)
+		node := &Node{Line: lineNumber, raw: raw, indent: leadingSpace(raw[0]), Parent: current}
+
+		switch {
+		case trimmed == "":
+			node.Kind = Blank
+		case strings.HasPrefix(trimmed, "#"):
+			node.Kind = Comment
+		case strings.HasPrefix(trimmed, "</"):
+			name := strings.TrimSpace(strings.TrimSuffix(trimmed[2:], ">"))
+			if current == f.Root || !strings.EqualFold(name, current.Name) {
+				return nil, fmt.Errorf("%s:%d: unexpected </%s>", path, lineNumber, name)
+			}
+			current.closing = raw
+			current = current.Parent
+			continue
+		case strings.HasPrefix(trimmed, "<"):
+			if !strings.HasSuffix(trimmed, ">") {
+				return nil, fmt.Errorf("%s:%d: section not closed with '>'", path, lineNumber)
+			}
+			fields, err := splitArgs(trimmed[1 : len(trimmed)-1])
+			if err != nil || len(fields) == 0 {
+				return nil, fmt.Errorf("%s:%d: invalid section", path, lineNumber)
+			}
+			node.Kind = Section
+			node.Name, node.Args = fields[0], fields[1:]
+		default:
+			fields, err := splitArgs(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", path, lineNumber, err.Error())
+			}
+			node.Kind = Directive
+			node.Name, node.Args = fields[0], fields[1:]
+		}
+
+		current.Children = append(current.Children, node)
+		if node.Kind == Section {
+			current = node
+		}
+	}
+
+	if current != f.Root {
+		return nil, fmt.Errorf("%s:%d: <%s> is never closed", path, current.Line, current.Name)
+	}
+
+	return f, nil
+}
+
+// Bytes returns the configuration, with the untouched lines exactly as read.
+func (f *File) Bytes() []byte {
+	var b strings.Builder
+	for _, child := range f.Root.Children {
+		child.render(&b)
+	}
+
+	out := b.String()
+	if !f.trailingNewline {
+		out = strings.TrimSuffix(out, "\n")
+	}
+	return []byte(out)
+}
+
+// Save writes the configuration back to its file.
+func (f *File) Save() error {
+	return utils.WriteFileAtomic(f.Path, f.Bytes(), 0644)
+}
+
+// ResolveIncludes parses the files pulled in by the Include and IncludeOptional
+// directives, recursively, and attaches them to the directives. Relative
+// patterns are relative to the ServerRoot; every path is taken under root, the
+// directory the whole layout lives in ("/" on a live system).
+func (f *File) ResolveIncludes(root string) error {
+	return f.resolveIncludes(root, f.ServerRoot(root), 0)
+}
+
+// ServerRoot returns the ServerRoot of the configuration, outside of root. When
+// the file has no ServerRoot directive, as on Debian, it is the directory of
+// the file.
+func (f *File) ServerRoot(root string) string {
+	if directive := f.Root.Find("ServerRoot"); directive != nil && len(directive.Args) > 0 {
+		return directive.Args[0]
+	}
+
+	dir := filepath.Dir(f.Path)
+	if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("/", rel)
+	}
+	return dir
+}
+
+func (f *File) resolveIncludes(root, serverRoot string, depth int) error {
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d levels deep", f.Path, maxIncludeDepth)
+	}
+
+	// The included files resolve their own includes: each file is loaded once
+	var err error
+	walk(f.Root.Children, false, func(n *Node) bool {
+		if err != nil || !(n.Is("Include") || n.Is("IncludeOptional")) || len(n.Args) == 0 {
+			return err == nil
+		}
+
+		pattern := n.Args[0]
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(serverRoot, pattern)
+		}
+
+		matches, globErr := filepath.Glob(filepath.Join(root, pattern))
+		if globErr != nil {
+			err = fmt.Errorf("%s:%d: invalid include pattern %s", f.Path, n.Line, n.Args[0])
+			return false
+		}
+
+		n.Included = nil
+		for _, match := range matches {
+			if info, statErr := os.Stat(match); statErr != nil || info.IsDir() {
+				continue
+			}
+
+			included, loadErr := Load(match)
+			if loadErr != nil {
+				err = loadErr
+				return false
+			}
+			if err = included.resolveIncludes(root, serverRoot, depth+1); err != nil {
+				return false
+			}
+			n.Included = append(n.Included, included)
+		}
+		return true
+	})
+
+	return err
+}
+
+// splitArgs splits a line into words, honouring double and single quotes.
+func splitArgs(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == '\\' && i+1 < len(runes) && runes[i+1] == quote:
+			current.WriteRune(quote)
+			i++
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case (r == '"' || r == '\'') && !inWord:
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				fields = append(fields, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// leadingSpace returns the indentation of a line.
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,92 @@
+package apacheconf
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// httpdConf is a stock Homebrew httpd.conf, abridged.
+const httpdConf = `ServerRoot "/opt/homebrew/opt/httpd"
+Listen 8080
+
+LoadModule mpm_prefork_module lib/httpd/modules/mod_mpm_prefork.so
+    LoadModule authz_core_module lib/httpd/modules/mod_authz_core.so
+#LoadModule ssl_module lib/httpd/modules/mod_ssl.so
+	# LoadModule http2_module lib/httpd/modules/mod_http2.so
+LoadModule rewrite_module \
+    lib/httpd/modules/mod_rewrite.so
+
+<IfModule unixd_module>
+    User _www
+    Group _www
+</IfModule>
+
+<Directory "/opt/homebrew/var/www">
+    Options Indexes FollowSymLinks
+    AllowOverride None
+</Directory>
+
+# Secure (SSL/TLS) connections
+#Include /opt/homebrew/etc/httpd/extra/httpd-ssl.conf
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, content := range []string{
+		httpdConf,
+		"",
+		"Listen 80",
+		"Listen 80\n\n",
+		"<IfModule ssl_module>\n\tSSLRandomSeed startup builtin\n</ifmodule>\n",
+	} {
+		f, err := Parse("httpd.conf", []byte(content))
+		if err != nil {
+			t.Fatalf("%q: %v", content, err)
+		}
+		if got := string(f.Bytes()); got != content {
+			t.Errorf("round trip of %q gave %q", content, got)
+		}
+	}
+}
+
+func TestParseCommentedAndIndentedLoadModule(t *testing.T) {
+	f, err := Parse("httpd.conf", []byte(httpdConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for module, loaded := range map[string]bool{
+		"mpm_prefork_module": true,
+		"authz_core_module":  true, // Indented
+		"rewrite_module":     true, // Continued on the next line
+		"ssl_module":         false,
+		"http2_module":       false,
+	} {
+		if f.ModuleLoaded(module) != loaded {
+			t.Errorf("ModuleLoaded(%s) = %v, want %v", module, !loaded, loaded)
+		}
+	}
+}
+
+func TestResolveNestedIncludes(t *testing.T) {
+	root := t.TempDir()
+
+	// conf.d/0.conf includes conf.d/1.conf, which includes conf.d/2.conf...
+	const levels = 12
+	writeFile(t, filepath.Join(root, "etc/httpd/httpd.conf"), "ServerRoot /etc/httpd\nInclude conf.d/0.conf\n")
+	for i := 0; i < levels; i++ {
+		writeFile(t, filepath.Join(root, fmt.Sprintf("etc/httpd/conf.d/%d.conf", i)), fmt.Sprintf("Define level%d\nInclude conf.d/%d.conf\n", i, i+1))
+	}
+	writeFile(t, filepath.Join(root, fmt.Sprintf("etc/httpd/conf.d/%d.conf", levels)), "LoadModule ssl_module modules/mod_ssl.so\n")
+
+	f, err := LoadResolved(filepath.Join(root, "etc/httpd/httpd.conf"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.Directives("LoadModule")); got != 1 {
+		t.Errorf("the innermost file's LoadModule is seen %d times", got)
+	}
+	if got := len(f.Directives("Define")); got != levels {
+		t.Errorf("%d Define directives, want %d", got, levels)
+	}
+}
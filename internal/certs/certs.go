@@ -0,0 +1,230 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+// KeyType is the kind of key a certificate is issued for.
+type KeyType string
+
+const (
+	ECDSA KeyType = "ecdsa" // P-256
+	RSA   KeyType = "rsa"   // 2048 bits
+)
+
+// ParseKeyType validates a key type setting.
+func ParseKeyType(value string) (KeyType, error) {
+	switch KeyType(strings.ToLower(value)) {
+	case ECDSA:
+		return ECDSA, nil
+	case RSA:
+		return RSA, nil
+	default:
+		return "", fmt.Errorf("unknown key type '%s' (expected ecdsa or rsa)", value)
+	}
+}
+
+// Options describe the certificates to create.
+type Options struct {
+	KeyType  KeyType
+	Validity time.Duration
+	Subject  pkix.Name // Every field but the common name, which is set per certificate
+}
+
+const (
+	// DefaultValidity is the longest validity macOS accepts for a TLS server certificate.
+	DefaultValidity = 825 * 24 * time.Hour
+
+	// CAValidity is the validity of the root certificate of a certificate authority.
+	CAValidity = 10 * 365 * 24 * time.Hour
+
+	// DefaultOrganization is the organization of the subjects.
+	DefaultOrganization = "localhost development CA"
+)
+
+// DefaultOptions returns ECDSA keys and the longest validity browsers accept.
+func DefaultOptions() Options {
+	return Options{
+		KeyType:  ECDSA,
+		Validity: DefaultValidity,
+		Subject:  pkix.Name{Organization: []string{DefaultOrganization}},
+	}
+}
+
+// GenerateKey creates a private key of the type.
+func GenerateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case ECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case RSA:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, fmt.Errorf("unknown key type '%s'", keyType)
+	}
+}
+
+// serialNumber returns a random 128-bit serial number, as browsers require
+// unique serial numbers per issuer.
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a serial number: %s", err.Error())
+	}
+	return serial, nil
+}
+
+// NewCA creates the root certificate and key of a certificate authority named
+// commonName. It may only sign leaf certificates.
+func NewCA(commonName string, opts Options) (*x509.Certificate, crypto.Signer, error) {
+	key, err := GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate the CA key: %s", err.Error())
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subject := opts.Subject
+	subject.CommonName = commonName
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-time.Hour), // Tolerate clocks slightly behind
+		NotAfter:              now.Add(opts.Validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	cert, err := sign(template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// NewLeaf creates a server certificate and key for the names, signed by the
+// certificate authority. The first name is the subject's common name; every name
+// is a subject alternative name, the only field browsers look at.
+func NewLeaf(ca *x509.Certificate, caKey crypto.Signer, names []string, opts Options) (*x509.Certificate, crypto.Signer, error) {
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("a certificate needs at least one name")
+	}
+
+	key, err := GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate the key for %s: %s", names[0], err.Error())
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subject := opts.Subject
+	subject.CommonName = names[0]
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(opts.Validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	// A leaf certificate cannot outlive its issuer
+	if template.NotAfter.After(ca.NotAfter) {
+		template.NotAfter = ca.NotAfter
+	}
+
+	cert, err := sign(template, ca, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// sign creates the certificate and parses it back.
+func sign(template, parent *x509.Certificate, public crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, public, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the certificate for %s: %s", template.Subject.CommonName, err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the certificate for %s: %s", template.Subject.CommonName, err.Error())
+	}
+	return cert, nil
+}
+
+// Covers reports whether the name is one of the certificate's subject
+// alternative names. Wildcards must match exactly: *.example.local is only
+// covered by *.example.local.
+func Covers(cert *x509.Certificate, name string) bool {
+	if ip := net.ParseIP(name); ip != nil {
+		for _, certIP := range cert.IPAddresses {
+			if certIP.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, dnsName := range cert.DNSNames {
+		if strings.EqualFold(dnsName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the subject alternative names of the certificate, DNS names first.
+func Names(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// KeyDescription describes the public key of the certificate, e.g. "ECDSA P-256" or "RSA 2048".
+func KeyDescription(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
@@ -0,0 +1,274 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testOptions returns the options of a test certificate.
+func testOptions(keyType KeyType, validity time.Duration) Options {
+	opts := DefaultOptions()
+	opts.KeyType = keyType
+	opts.Validity = validity
+	opts.Subject = pkix.Name{Organization: []string{"test org"}}
+	return opts
+}
+
+// saveAndLoad writes the certificate and key to a temporary directory and
+// parses them back, as the tool and Apache read them.
+func saveAndLoad(t *testing.T, cert *x509.Certificate, key crypto.Signer) (*x509.Certificate, crypto.Signer, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "sub", "server.crt"), filepath.Join(dir, "sub", "server.key")
+	if err := Save(certFile, keyFile, cert, key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loadedCert, err := LoadCertificate(certFile)
+	if err != nil {
+		t.Fatalf("LoadCertificate: %v", err)
+	}
+	loadedKey, err := LoadKey(keyFile)
+	if err != nil {
+		t.Fatalf("LoadKey: %v", err)
+	}
+	return loadedCert, loadedKey, keyFile
+}
+
+func TestNewCAAndLeaf(t *testing.T) {
+	for _, keyType := range []KeyType{ECDSA, RSA} {
+		t.Run(string(keyType), func(t *testing.T) {
+			start := time.Now()
+
+			caCert, caKey, err := NewCA("test Root CA", testOptions(keyType, CAValidity))
+			if err != nil {
+				t.Fatalf("NewCA: %v", err)
+			}
+			ca, caKeyLoaded, _ := saveAndLoad(t, caCert, caKey)
+
+			if !ca.IsCA || !ca.MaxPathLenZero || ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+				t.Errorf("CA constraints: IsCA=%v MaxPathLenZero=%v KeyUsage=%v", ca.IsCA, ca.MaxPathLenZero, ca.KeyUsage)
+			}
+			if ca.Subject.CommonName != "test Root CA" || len(ca.Subject.Organization) != 1 || ca.Subject.Organization[0] != "test org" {
+				t.Errorf("CA subject = %v", ca.Subject)
+			}
+			if err := ca.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("CA is not self-signed: %v", err)
+			}
+
+			names := []string{"myproject.local", "www.myproject.local", "*.myproject.local", "127.0.0.1", "::1"}
+			leafCert, leafKey, err := NewLeaf(ca, caKeyLoaded, names, testOptions(keyType, DefaultValidity))
+			if err != nil {
+				t.Fatalf("NewLeaf: %v", err)
+			}
+			leaf, leafKeyLoaded, keyFile := saveAndLoad(t, leafCert, leafKey)
+
+			// Key type and usages
+			switch keyType {
+			case ECDSA:
+				if key, ok := leaf.PublicKey.(*ecdsa.PublicKey); !ok || key.Curve != elliptic.P256() {
+					t.Errorf("leaf key is %T, want an ECDSA P-256 key", leaf.PublicKey)
+				}
+				if leaf.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+					t.Errorf("ECDSA leaf has KeyEncipherment")
+				}
+			case RSA:
+				if key, ok := leaf.PublicKey.(*rsa.PublicKey); !ok || key.N.BitLen() != 2048 {
+					t.Errorf("leaf key is %T, want a 2048-bit RSA key", leaf.PublicKey)
+				}
+				if leaf.KeyUsage&x509.KeyUsageKeyEncipherment == 0 {
+					t.Errorf("RSA leaf lacks KeyEncipherment")
+				}
+			}
+			if !MatchesKey(leaf, leafKeyLoaded) {
+				t.Errorf("saved key does not match the saved certificate")
+			}
+			if leaf.IsCA || len(leaf.ExtKeyUsage) != 1 || leaf.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+				t.Errorf("leaf usages: IsCA=%v ExtKeyUsage=%v", leaf.IsCA, leaf.ExtKeyUsage)
+			}
+
+			// Subject alternative names
+			if leaf.Subject.CommonName != "myproject.local" {
+				t.Errorf("leaf common name = %q", leaf.Subject.CommonName)
+			}
+			wantDNS := []string{"myproject.local", "www.myproject.local", "*.myproject.local"}
+			if len(leaf.DNSNames) != len(wantDNS) {
+				t.Fatalf("DNS names = %v, want %v", leaf.DNSNames, wantDNS)
+			}
+			for i, name := range wantDNS {
+				if leaf.DNSNames[i] != name {
+					t.Errorf("DNS name %d = %q, want %q", i, leaf.DNSNames[i], name)
+				}
+			}
+			if len(leaf.IPAddresses) != 2 || !leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) || !leaf.IPAddresses[1].Equal(net.ParseIP("::1")) {
+				t.Errorf("IP addresses = %v, want [127.0.0.1 ::1]", leaf.IPAddresses)
+			}
+			for _, name := range names {
+				if !Covers(leaf, name) {
+					t.Errorf("Covers(%q) = false", name)
+				}
+			}
+			if Covers(leaf, "other.local") || Covers(leaf, "10.0.0.1") {
+				t.Errorf("Covers reports a name that is not in the certificate")
+			}
+
+			// Validity
+			if leaf.NotBefore.After(start) {
+				t.Errorf("NotBefore %v is after the issuance time %v", leaf.NotBefore, start)
+			}
+			if want := start.Add(DefaultValidity); leaf.NotAfter.Before(want.Add(-time.Minute)) || leaf.NotAfter.After(want.Add(time.Minute)) {
+				t.Errorf("NotAfter = %v, want about %v", leaf.NotAfter, want)
+			}
+
+			// Chain, including the wildcard and IP names, as a TLS client checks it
+			if err := leaf.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("leaf is not signed by the CA: %v", err)
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(ca)
+			for _, host := range []string{"myproject.local", "api.myproject.local", "127.0.0.1", "::1"} {
+				if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+					t.Errorf("Verify(%s): %v", host, err)
+				}
+			}
+
+			// The key is only readable by its owner
+			info, err := os.Stat(keyFile)
+			if err != nil {
+				t.Fatalf("stat key: %v", err)
+			}
+			if mode := info.Mode().Perm(); mode != 0600 {
+				t.Errorf("key mode = %o, want 600", mode)
+			}
+		})
+	}
+}
+
+func TestNewLeafClampedToCA(t *testing.T) {
+	caCert, caKey, err := NewCA("short CA", testOptions(ECDSA, 48*time.Hour))
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	leaf, _, err := NewLeaf(caCert, caKey, []string{"clamped.local"}, testOptions(ECDSA, DefaultValidity))
+	if err != nil {
+		t.Fatalf("NewLeaf: %v", err)
+	}
+	if !leaf.NotAfter.Equal(caCert.NotAfter) {
+		t.Errorf("leaf NotAfter = %v, want the CA's %v", leaf.NotAfter, caCert.NotAfter)
+	}
+}
+
+func TestNewLeafWithoutNames(t *testing.T) {
+	caCert, caKey, err := NewCA("test CA", testOptions(ECDSA, CAValidity))
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+	if _, _, err := NewLeaf(caCert, caKey, nil, testOptions(ECDSA, DefaultValidity)); err == nil {
+		t.Errorf("NewLeaf without names: expected an error")
+	}
+}
+
+func TestSaveForcesKeyMode(t *testing.T) {
+	cert, key, err := NewCA("test CA", testOptions(ECDSA, CAValidity))
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+
+	// A previous key left world-readable is replaced by a private one
+	if err := os.WriteFile(keyFile, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(certFile, keyFile, cert, key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	for file, want := range map[string]os.FileMode{keyFile: 0600, certFile: 0644} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != want {
+			t.Errorf("%s mode = %o, want %o", filepath.Base(file), mode, want)
+		}
+	}
+}
+
+func TestLoadKeyFormats(t *testing.T) {
+	ecKey, err := GenerateKey(ECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := GenerateKey(RSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec1, err := x509.MarshalECPrivateKey(ecKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8EC, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8RSA, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		block pem.Block
+		want  crypto.Signer
+	}{
+		{"PKCS #1 RSA", pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))}, rsaKey},
+		{"SEC 1 EC", pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, ecKey},
+		{"PKCS #8 EC", pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8EC}, ecKey},
+		{"PKCS #8 RSA", pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8RSA}, rsaKey},
+	} {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&tc.block), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		key, err := LoadKey(path)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !public.Equal(tc.want.Public()) {
+			t.Errorf("%s: loaded a different key", tc.name)
+		}
+	}
+
+	// Neither an unknown block type nor a file without PEM is accepted
+	for name, data := range map[string][]byte{
+		"unsupported block": pem.EncodeToMemory(&pem.Block{Type: "DSA PRIVATE KEY", Bytes: []byte{0}}),
+		"not PEM":           []byte("not a key"),
+		"corrupt DER":       pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}}),
+	} {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKey(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
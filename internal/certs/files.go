@@ -0,0 +1,149 @@
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoadCertificate reads a PEM certificate.
+func LoadCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s does not contain a PEM certificate", path)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err.Error())
+	}
+	return cert, nil
+}
+
+// LoadKey reads a PEM private key, in the PKCS #8 form Save writes or in the
+// older PKCS #1 and SEC 1 forms openssl used to write.
+func LoadKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM key", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s holds an unsupported %s", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err.Error())
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s holds an unsupported key", path)
+	}
+	return signer, nil
+}
+
+// Save writes the certificate (0644) and its key (0600, whatever the mode of a
+// previous file) as PEM, then reads both back to make sure they were written
+// whole and belong together.
+func Save(certFile, keyFile string, cert *x509.Certificate, key crypto.Signer) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode the key of %s: %s", cert.Subject.CommonName, err.Error())
+	}
+
+	if err := writeFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := writeFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644); err != nil {
+		return err
+	}
+
+	return verifySaved(certFile, keyFile, cert)
+}
+
+// verifySaved parses the files back and checks them against the certificate.
+func verifySaved(certFile, keyFile string, cert *x509.Certificate) error {
+	saved, err := LoadCertificate(certFile)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(saved.Raw, cert.Raw) {
+		return fmt.Errorf("%s does not hold the certificate that was written", certFile)
+	}
+
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+	if !MatchesKey(saved, key) {
+		return fmt.Errorf("%s does not match the certificate in %s", keyFile, certFile)
+	}
+
+	return nil
+}
+
+// MatchesKey reports whether the key is the private key of the certificate.
+func MatchesKey(cert *x509.Certificate, key crypto.Signer) bool {
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(cert.PublicKey)
+}
+
+// writeFile atomically replaces the file with data, forcing its permissions.
+// Keys are never backed up: a copy of a private key is one more to protect.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %s", dir, err.Error())
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %s", path, err.Error())
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	// CreateTemp already restricts the file to its owner; the key never exists with a wider mode
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions on %s: %s", path, err.Error())
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %s", path, err.Error())
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to flush %s to disk: %s", path, err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file for %s: %s", path, err.Error())
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %s", path, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/system"
+	"github.com/liviu-hariton/localhost/internal/trust"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// caTrustName is the file name and nickname of the local certificate authority in the trust stores.
+const caTrustName = "localhost-root-ca"
+
+func CACommand(args []string) {
+	if len(args) < 1 {
+		utils.LogWarning("Please provide a ca subcommand. For example:")
+		fmt.Println("    localhost ca install")
+		fmt.Println("    localhost ca uninstall")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "install":
+		caInstall(args[1:])
+	case "uninstall":
+		caUninstall(args[1:])
+	default:
+		utils.LogWarning(fmt.Sprintf("Unknown ca subcommand '%s'. Use 'help' for usage information.", args[0]))
+		os.Exit(1)
+	}
+}
+
+// caInstall makes the OS and the browsers trust the local certificate authority
+func caInstall(args []string) {
+	flagSet := flag.NewFlagSet("ca install", flag.ExitOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any trust store")
+	flagSet.Parse(args)
+
+	utils.SetDryRun(*dryRun)
+
+	if err := system.EnsureCA(); err != nil {
+		utils.LogError("Creating the local certificate authority", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(paths.Get().CACertificateFile()); os.IsNotExist(err) && utils.IsDryRun() {
+		utils.LogInfo("DRY RUN: Would add the new local certificate authority to the trust stores.")
+		return
+	}
+
+	applyToTrustStores(func(store trust.Store, ca trust.CA) error {
+		if utils.IsDryRun() {
+			fmt.Printf("DRY RUN: Would add the local certificate authority to the %s.\n", store.Name())
+			return nil
+		}
+		if err := store.Install(ca); err != nil {
+			return err
+		}
+		fmt.Printf("✔ The %s trusts the local certificate authority.\n", store.Name())
+		return nil
+	})
+
+	utils.LogSuccess("Restart your browsers for the change to take effect.")
+}
+
+// caUninstall removes the local certificate authority from the trust stores; the
+// authority itself is kept, so that the sites' certificates stay valid
+func caUninstall(args []string) {
+	flagSet := flag.NewFlagSet("ca uninstall", flag.ExitOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any trust store")
+	flagSet.Parse(args)
+
+	utils.SetDryRun(*dryRun)
+
+	applyToTrustStores(func(store trust.Store, ca trust.CA) error {
+		if utils.IsDryRun() {
+			fmt.Printf("DRY RUN: Would remove the local certificate authority from the %s.\n", store.Name())
+			return nil
+		}
+		if err := store.Uninstall(ca); err != nil {
+			return err
+		}
+		fmt.Printf("✔ Removed the local certificate authority from the %s.\n", store.Name())
+		return nil
+	})
+
+	utils.LogSuccess("The local certificate authority is no longer trusted.")
+}
+
+// applyToTrustStores runs the action on every trust store found on this machine,
+// exiting with an error once all of them were tried if any failed
+func applyToTrustStores(action func(store trust.Store, ca trust.CA) error) {
+	cert, err := certs.LoadCertificate(paths.Get().CACertificateFile())
+	if err != nil {
+		utils.LogError("Loading the local certificate authority", err)
+		os.Exit(1)
+	}
+	ca := trust.CA{Cert: cert, File: paths.Get().CACertificateFile(), Name: caTrustName}
+
+	stores := trust.AvailableStores(trust.Config{Root: paths.Get().Root, Home: utils.GetOriginalHome()})
+	if len(stores) == 0 {
+		utils.LogWarning("No supported trust store was found on this machine.")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, store := range stores {
+		if err := action(store, ca); err != nil {
+			utils.LogError(fmt.Sprintf("Updating the %s", store.Name()), err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
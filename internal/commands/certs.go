@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/config"
+	"github.com/liviu-hariton/localhost/internal/system"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func CertsCommand(args []string) {
+	if len(args) < 1 {
+		utils.LogWarning("Please provide a certs subcommand. For example:")
+		fmt.Println("    localhost certs list")
+		fmt.Println("    localhost certs renew --all")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		certsList(args[1:])
+	case "renew":
+		certsRenew(args[1:])
+	default:
+		utils.LogWarning(fmt.Sprintf("Unknown certs subcommand '%s'. Use 'help' for usage information.", args[0]))
+		os.Exit(1)
+	}
+}
+
+// certsList prints every certificate issued by the tool with its names, issuer, key type and days left
+func certsList(args []string) {
+	flagSet := flag.NewFlagSet("certs list", flag.ExitOnError)
+	flagSet.Parse(args)
+
+	window := system.CertificateWarnWindow()
+
+	list, err := system.Certificates()
+	if err != nil {
+		utils.LogError("Listing the certificates", err)
+		os.Exit(1)
+	}
+	if len(list) == 0 {
+		utils.LogInfo("No certificate was issued yet. Run 'localhost create' to issue one.")
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "DOMAIN\tNAMES\tISSUER\tKEY\tEXPIRES\tDAYS LEFT")
+	for _, cert := range list {
+		if cert.Cert == nil {
+			fmt.Fprintf(table, "%s\t-\t-\t-\t-\t%s\n", cert.Label(), cert.Err.Error())
+			continue
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%d\n",
+			cert.Label(),
+			strings.Join(certs.Names(cert.Cert), ", "),
+			cert.Cert.Issuer.CommonName,
+			certs.KeyDescription(cert.Cert),
+			cert.Cert.NotAfter.Format("2006-01-02"),
+			cert.DaysLeft())
+	}
+	table.Flush()
+
+	for _, cert := range list {
+		if cert.Cert != nil && cert.ExpiresWithin(window) {
+			utils.LogWarning(cert.ExpiryWarning(window))
+		} else if problem := cert.Problem(); cert.Cert != nil && problem != "" {
+			utils.LogWarning(fmt.Sprintf("The certificate of '%s' %s. Run 'localhost certs renew' for it.", cert.Label(), problem))
+		}
+	}
+}
+
+// certsRenew issues new certificates for the ones expiring within the window, or
+// that cannot be served anymore, then reloads Apache gracefully
+func certsRenew(args []string) {
+	flagSet := flag.NewFlagSet("certs renew", flag.ExitOnError)
+	domain := flagSet.String("domain", "", "The domain whose certificate to renew")
+	all := flagSet.Bool("all", false, "Renew every certificate issued by the tool that needs it, including the default one")
+	within := flagSet.String("within", "", "Renew the certificates expiring within this many days, e.g. 30d (defaults to the cert_warn_days setting)")
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without issuing certificates")
+	flagSet.Parse(args)
+
+	utils.SetDryRun(*dryRun)
+
+	if (*domain == "") == !*all {
+		utils.LogWarning("Please provide either -domain or --all. For example:")
+		fmt.Println("    localhost certs renew -domain=myproject.local")
+		fmt.Println("    localhost certs renew --all --within=60d")
+		os.Exit(1)
+	}
+
+	if *domain != "" {
+		if err := config.ValidateHostname(*domain); err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -domain flag: %s", err))
+			os.Exit(1)
+		}
+	}
+
+	var err error
+	window := system.CertificateWarnWindow()
+	if *within != "" {
+		if window, err = utils.ParseDays(*within); err != nil {
+			utils.LogError("Reading the renewal window", err)
+			os.Exit(1)
+		}
+	}
+
+	var list []system.Certificate
+	if *all {
+		list, err = system.Certificates()
+	} else {
+		var cert system.Certificate
+		cert, err = system.SiteCertificate(*domain)
+		list = append(list, cert)
+	}
+	if err != nil {
+		utils.LogError("Finding the certificates", err)
+		os.Exit(1)
+	}
+
+	renewed, failed := 0, false
+	for _, cert := range list {
+		if cert.Cert == nil {
+			utils.LogError(fmt.Sprintf("Loading the certificate of '%s'", cert.Label()), cert.Err)
+			failed = true
+			continue
+		}
+
+		problem := cert.Problem()
+		if problem == "" && !cert.ExpiresWithin(window) {
+			utils.LogSuccess(fmt.Sprintf("The certificate of '%s' is valid for %d more day(s), nothing to renew.", cert.Label(), cert.DaysLeft()))
+			continue
+		}
+
+		if problem != "" {
+			utils.LogInfo(fmt.Sprintf("Renewing the certificate of '%s', which %s...", cert.Label(), problem))
+		} else {
+			utils.LogInfo(fmt.Sprintf("Renewing the certificate of '%s', which expires in %d day(s)...", cert.Label(), cert.DaysLeft()))
+		}
+
+		if err := system.RenewCertificate(cert); err != nil {
+			utils.LogError(fmt.Sprintf("Renewing the certificate of '%s'", cert.Label()), err)
+			failed = true
+			continue
+		}
+		if !utils.IsDryRun() {
+			fmt.Printf("✔ Renewed the certificate of '%s'.\n", cert.Label())
+		}
+		renewed++
+	}
+
+	if renewed > 0 {
+		utils.LogInfo("Applying the changes to Apache...")
+
+		// One graceful reload for every renewed certificate
+		if err := system.ApplyApacheChanges(); err != nil {
+			utils.LogError(fmt.Sprintf("Apache Error: %s\n", err), err)
+			os.Exit(1)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	utils.LogSuccess(fmt.Sprintf("%d certificate(s) renewed.", renewed))
+}
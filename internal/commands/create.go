@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/liviu-hariton/localhost/internal/config"
+	"github.com/liviu-hariton/localhost/internal/presets"
 	"github.com/liviu-hariton/localhost/internal/system"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
@@ -15,16 +18,115 @@ func CreateCommand(args []string) {
 	flagSet := flag.NewFlagSet("create", flag.ExitOnError)
 	domain := flagSet.String("domain", "", "The local domain to set up (e.g., myproject.local)")
 	docRoot := flagSet.String("doc_root", "", "The document root for the virtual host")
+	family := flagSet.String("family", "both", "The address families to set up: both, ipv4 or ipv6")
+	presetName := flagSet.String("preset", "", fmt.Sprintf("The kind of project: %s (detected from doc_root when omitted)", strings.Join(presets.Names(), ", ")))
+	templateName := flagSet.String("template", "", "The vhost template to use, 'default' or 'proxy' with -proxy; templates in ~/.config/localhost/templates override the built-in ones")
+	phpVersion := flagSet.String("php", "", "Run the site's PHP through the PHP-FPM of this version (e.g., 8.1) instead of the global PHP module")
+	proxy := flagSet.String("proxy", "", "Forward the site to a local dev server instead of serving files (e.g., http://127.0.0.1:3000)")
+	wildcard := flagSet.Bool("wildcard", false, "Also answer on every subdomain (*.domain) and cover them in the site's certificate")
+	tlsName := flagSet.String("tls", config.DefaultTLSProfile, fmt.Sprintf("The TLS profile of the HTTPS port: %s", strings.Join(config.TLSProfileNames(), ", ")))
+	http2 := flagSet.Bool("http2", false, "Offer HTTP/2 on the HTTPS port, enabling mod_http2")
+	var aliases, ips utils.StringList
+	flagSet.Var(&aliases, "alias", "An additional name the site answers on, e.g. www.myproject.local (repeatable)")
+	flagSet.Var(&ips, "ip", "The IPv4 or IPv6 address the domain points to (repeatable, defaults to the loopback addresses)")
 	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any files or directories")
 	flagSet.Parse(args)
 
-	// Validate required flags
-	if *domain == "" || *docRoot == "" {
+	// Resolve the addresses the domain points to
+	addresses, err := config.LoopbackAddresses(*family)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Invalid -family flag: %s", err))
+		os.Exit(1)
+	}
+
+	local := true
+	if len(ips) > 0 {
+		addresses, local, err = config.ParseAddresses(ips)
+		if err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -ip flag: %s", err))
+			os.Exit(1)
+		}
+	}
+
+	proxyURL := ""
+	if *proxy != "" {
+		if proxyURL, err = config.ParseProxyURL(*proxy); err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -proxy flag: %s", err))
+			os.Exit(1)
+		}
+		if *presetName != "" || !local {
+			utils.LogWarning("The -proxy flag cannot be combined with -preset or -ip.")
+			os.Exit(1)
+		}
+	}
+
+	// Validate required flags; a site served elsewhere needs no document root
+	if *domain == "" || (local && proxyURL == "" && *docRoot == "") {
 		utils.LogWarning("Please provide both -domain and -doc_root flags. For example:")
 		utils.LogWarning("    go run main.go create -domain=myproject.local -doc_root=/path/on/disk/to/myproject")
+		utils.LogWarning("    go run main.go create -domain=myproject.local -ip=192.168.1.20")
+		utils.LogWarning("    go run main.go create -domain=myproject.local -proxy=http://127.0.0.1:3000")
+		os.Exit(1)
+	}
+
+	for _, name := range append([]string{*domain}, aliases...) {
+		if err := config.ValidateHostname(name); err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -domain or -alias flag: %s", err))
+			os.Exit(1)
+		}
+	}
+
+	for _, alias := range aliases {
+		if strings.EqualFold(alias, *domain) {
+			utils.LogWarning(fmt.Sprintf("The alias '%s' is the same as the domain.", alias))
+			os.Exit(1)
+		}
+	}
+
+	// An explicit preset wins over the one detected from the document root.
+	// Reverse proxy sites serve no files, so they have no preset.
+	var detection presets.Detection
+	var preset presets.Preset
+	detecting := proxyURL == "" && *presetName == "" && local
+	if proxyURL == "" {
+		if detecting {
+			detection = presets.Detect(*docRoot)
+			*presetName = detection.Preset
+		}
+		if *presetName == "" {
+			*presetName = presets.DefaultPreset
+		}
+
+		if preset, err = presets.Get(*presetName); err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -preset flag: %s", err))
+			os.Exit(1)
+		}
+	}
+
+	if *phpVersion != "" {
+		if err := system.ValidatePHPVersion(*phpVersion); err != nil {
+			utils.LogWarning(fmt.Sprintf("Invalid -php flag: %s", err))
+			os.Exit(1)
+		}
+		if !preset.Needs(presets.PHP) {
+			utils.LogWarning("The -php flag needs a PHP preset and cannot be combined with -proxy.")
+			os.Exit(1)
+		}
+	}
+
+	tls, err := config.TLSProfile(*tlsName)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Invalid -tls flag: %s", err))
 		os.Exit(1)
 	}
 
+	if *templateName == "" {
+		*templateName = config.DefaultTemplate
+		if proxyURL != "" {
+			*templateName = config.ProxyTemplate
+		}
+	}
+
 	// Set dry run mode
 	utils.SetDryRun(*dryRun)
 	if *dryRun {
@@ -33,6 +135,31 @@ func CreateCommand(args []string) {
 
 	utils.LogInfo(fmt.Sprintf("Starting setup for domain: %s\n", *domain))
 
+	// The site is served by another machine or container: only the hosts file is needed
+	if !local {
+		utils.LogInfo(fmt.Sprintf("'%s' points to %s. Skipping the Apache virtual host setup.", *domain, strings.Join(addresses, ", ")))
+
+		if err := config.ModifyHosts(*domain, aliases, addresses); err != nil {
+			utils.LogError(fmt.Sprintf("Hosts File Error: %s\n", err), err)
+			return
+		}
+
+		utils.LogSuccess("All changes applied successfully!")
+		return
+	}
+
+	if proxyURL != "" {
+		utils.LogInfo(fmt.Sprintf("Forwarding '%s' to %s", *domain, proxyURL))
+	} else {
+		if len(detection.Evidence) > 0 {
+			utils.LogInfo(fmt.Sprintf("Detected the '%s' preset in %s: %s", preset.Name, *docRoot, strings.Join(detection.Evidence, ", ")))
+			utils.LogInfo("Pass -preset to choose another one.")
+		} else if detecting {
+			utils.LogInfo(fmt.Sprintf("%s does not exist yet: nothing to detect a preset from. Pass -preset to choose one.", *docRoot))
+		}
+		utils.LogInfo(fmt.Sprintf("Using the '%s' preset: %s", preset.Name, preset.Description))
+	}
+
 	fmt.Println("Starting system checks...")
 
 	// Check Apache
@@ -42,22 +169,33 @@ func CreateCommand(args []string) {
 	}
 
 	// Check MySQL
-	if err := system.VerifyMySQL(); err != nil {
-		utils.LogError(fmt.Sprintf("MySQL Error: %s\n", err), err)
-		return
+	if preset.Needs(presets.MySQL) {
+		if err := system.VerifyMySQL(); err != nil {
+			utils.LogError(fmt.Sprintf("MySQL Error: %s\n", err), err)
+			return
+		}
 	}
 
-	// Check PHP
-	if err := system.VerifyPHP(); err != nil {
-		utils.LogError(fmt.Sprintf("PHP Error: %s\n", err), err)
-		return
+	// Check PHP, either the global module or the PHP-FPM of the requested version
+	var fpm *system.PHPFPM
+	if *phpVersion != "" {
+		if fpm, err = system.VerifyPHPFPM(*phpVersion); err != nil {
+			utils.LogError(fmt.Sprintf("PHP Error: %s\n", err), err)
+			return
+		}
+	} else if preset.Needs(presets.PHP) {
+		if err := system.VerifyPHP(); err != nil {
+			utils.LogError(fmt.Sprintf("PHP Error: %s\n", err), err)
+			return
+		}
 	}
 
 	utils.LogSuccess("All checks passed successfully!")
 
 	// Modify Hosts File
-	if err := config.ModifyHosts(*domain); err != nil {
+	if err := config.ModifyHosts(*domain, aliases, addresses); err != nil {
 		utils.LogError(fmt.Sprintf("Hosts File Error: %s\n", err), err)
+		revertCreate()
 		return
 	}
 
@@ -66,26 +204,90 @@ func CreateCommand(args []string) {
 	// Ensure vhosts are enabled
 	if err := config.EnsureVhostsEnabled(); err != nil {
 		utils.LogError(fmt.Sprintf("Apache Config Error: %s\n", err), err)
+		revertCreate()
 		return
 	}
 
+	// Enable the modules reverse proxies need
+	if proxyURL != "" {
+		if err := system.EnableProxyModulesInHttpdConf(); err != nil {
+			utils.LogError(fmt.Sprintf("Apache Config Error: %s\n", err), err)
+			revertCreate()
+			return
+		}
+	}
+
+	// Enable the modules handing PHP requests to PHP-FPM
+	if fpm != nil {
+		if err := system.EnableFastCGIModulesInHttpdConf(); err != nil {
+			utils.LogError(fmt.Sprintf("Apache Config Error: %s\n", err), err)
+			revertCreate()
+			return
+		}
+	}
+
+	// Enable the module serving HTTP/2
+	if *http2 {
+		if err := system.EnableHTTP2ModuleInHttpdConf(); err != nil {
+			utils.LogError(fmt.Sprintf("Apache Config Error: %s\n", err), err)
+			revertCreate()
+			return
+		}
+	}
+
+	// Every name the site answers on, as served by the vhost and covered by its certificate
+	names := append([]string{*domain}, aliases...)
+	if *wildcard {
+		names = append(names, "*."+*domain)
+	}
+
 	// Add Virtual Host
-	if err := config.AddVirtualHost(*domain, *docRoot); err != nil {
+	data := config.NewVhostData(*domain, names[1:], *docRoot, addresses)
+	data.TLS = tls
+	data.HTTP2 = *http2
+	if proxyURL != "" {
+		data.ProxyURL = proxyURL
+		data.PublicDir = ""
+	} else {
+		preset.Apply(&data)
+	}
+	if fpm != nil {
+		data.PHPFPM = config.VhostPHPFPM{Version: fpm.Version, Socket: fpm.Socket}
+	}
+
+	if err := config.AddVirtualHost(data, *templateName); err != nil {
 		utils.LogError(fmt.Sprintf("Virtual Host Error: %s\n", err), err)
+		revertCreate()
 		return
 	}
 
-	utils.LogInfo("Restarting Apache to apply changes...")
+	system.RequestApacheReload()
 
-	// Restart Apache to apply changes
-	if err := system.RestartApache(); err != nil {
-		utils.LogError(fmt.Sprintf("Apache Restart Error: %s\n", err), err)
+	// Ensure SSL Certificates
+	if err := system.EnsureSSLCertificates(); err != nil {
+		utils.LogError(fmt.Sprintf("SSL Error: %s\n", err), err)
+		revertCreate()
 		return
 	}
 
-	// Ensure SSL Certificates
-	if err := system.EnsureSSLCertificates(); err != nil {
+	// Issue the site's own certificate from the local certificate authority
+	if err := system.EnsureSiteCertificate(*domain, names); err != nil {
 		utils.LogError(fmt.Sprintf("SSL Error: %s\n", err), err)
+		revertCreate()
+		return
+	}
+
+	utils.LogInfo("Applying the changes to Apache...")
+
+	// Reload (or restart, when a module was added) Apache once for the whole command
+	if err := system.ApplyApacheChanges(); err != nil {
+		utils.LogError(fmt.Sprintf("Apache Error: %s\n", err), err)
+
+		// Apache serves the site when only the DNS flush failed
+		var flushErr *system.DNSFlushError
+		if !errors.As(err, &flushErr) {
+			revertCreate()
+		}
 		return
 	}
 
@@ -93,3 +295,20 @@ func CreateCommand(args []string) {
 
 	utils.LogInfo(fmt.Sprintf("You should now be able to access your new project at http://%s or https://%s\n", *domain, *domain))
 }
+
+// revertCreate puts back every file and directory the command changed before
+// it failed, so that a failed create leaves no half-configured site behind.
+func revertCreate() {
+	if len(utils.ChangedFiles()) == 0 {
+		return
+	}
+
+	utils.LogWarning("Reverting the changes made by this command...")
+	reverted, err := utils.RevertChanges()
+	for _, path := range reverted {
+		fmt.Printf("✔ Reverted %s\n", path)
+	}
+	if err != nil {
+		utils.LogError("Reverting the changes", err)
+	}
+}
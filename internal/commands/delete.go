@@ -1,14 +1,12 @@
 package commands
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/config"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/system"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
@@ -26,6 +24,10 @@ func DeleteCommand(args []string) {
 		flagSet.Usage()
 		return
 	}
+	if err := config.ValidateHostname(*domain); err != nil {
+		utils.LogWarning(fmt.Sprintf("Invalid -domain flag: %s", err))
+		os.Exit(1)
+	}
 
 	utils.SetDryRun(*dryRun)
 	if utils.IsDryRun() {
@@ -39,102 +41,55 @@ func DeleteCommand(args []string) {
 		return
 	}
 
-	// Remove the virtual host configuration file
-	vhostsDir := "/opt/homebrew/etc/httpd/extra/vhosts/"
-	vhostFile := vhostsDir + *domain + ".conf"
+	// A site created with -ip only has hosts entries: Apache has nothing to reload
+	vhostFile := paths.Get().VhostFile(*domain)
+	if _, err := os.Stat(vhostFile); os.IsNotExist(err) {
+		utils.LogInfo(fmt.Sprintf("No virtual host is configured for '%s'. Only its hosts entries are removed.", *domain))
+		if err := config.RemoveDomainFromHosts(*domain); err != nil {
+			utils.LogError(fmt.Sprintf("Error modifying /etc/hosts: %s", err), err)
+			return
+		}
+		utils.LogSuccess(fmt.Sprintf("Successfully removed domain '%s' from /etc/hosts.", *domain))
+		return
+	}
 
-	if err := os.Remove(vhostFile); err != nil {
+	// Remove the virtual host configuration file
+	vhostRemoved := false
+	if err := utils.RemoveFile(vhostFile); err != nil {
 		utils.LogError(fmt.Sprintf("Error deleting domain configuration file: %s", err), err)
 	} else {
+		vhostRemoved = true
 		utils.LogSuccess(fmt.Sprintf("Successfully deleted virtual host configuration for domain '%s'.", *domain))
 	}
 
 	// Remove the domain from /etc/hosts
-	if err := removeDomainFromHosts(*domain); err != nil {
+	if err := config.RemoveDomainFromHosts(*domain); err != nil {
 		utils.LogError(fmt.Sprintf("Error modifying /etc/hosts: %s", err), err)
 		return
 	}
 
-	utils.LogInfo("Restarting Apache to apply changes...")
+	utils.LogInfo("Reloading Apache to apply changes...")
 
-	// Restart Apache to apply changes
-	if err := system.RestartApache(); err != nil {
-		utils.LogError(fmt.Sprintf("Apache Restart Error: %s", err), err)
+	// Reload Apache to apply changes
+	system.RequestApacheReload()
+	if err := system.ApplyApacheChanges(); err != nil {
+		utils.LogError(fmt.Sprintf("Apache Error: %s", err), err)
 		return
 	}
 
-	utils.LogSuccess(fmt.Sprintf("Successfully removed domain '%s' from /etc/hosts.", *domain))
-}
-
-// confirmAction prompts the user for confirmation before proceeding
-func confirmAction(domain string) bool {
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Printf("Are you sure you want to delete the domain '%s' and its references in /etc/hosts? (y/N): ", domain)
-	response, _ := reader.ReadString('\n')
-
-	// Normalize and trim input
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	return response == "y"
-}
-
-// removeDomainFromHosts removes references to a domain from /etc/hosts
-func removeDomainFromHosts(domain string) error {
-	hostsFilePath := "/etc/hosts"
-
-	// Open the /etc/hosts file for reading
-	file, err := os.Open(hostsFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to open /etc/hosts: %s", err.Error())
-	}
-	defer file.Close()
-
-	var newLines []string
-	scanner := bufio.NewScanner(file)
-
-	// Iterate through each line and exclude lines referencing the domain
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Ignore lines that reference the domain
-		if strings.Contains(line, domain) {
-			utils.LogInfo(fmt.Sprintf("Removing line from /etc/hosts: %s\n", line))
-			continue
+	// Remove the certificate issued for the domain only once Apache no longer
+	// uses it; it is not backed up since the local certificate authority can
+	// issue a new one at any time
+	if vhostRemoved {
+		if err := system.RemoveSiteCertificate(*domain); err != nil {
+			utils.LogError(fmt.Sprintf("Error deleting the certificate of the domain: %s", err), err)
 		}
-
-		newLines = append(newLines, line)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read /etc/hosts: %s", err.Error())
-	}
-
-	// Write the updated lines back to the file
-	if err := writeLinesToFile(newLines, hostsFilePath); err != nil {
-		return fmt.Errorf("failed to update /etc/hosts: %s", err.Error())
 	}
 
-	return nil
+	utils.LogSuccess(fmt.Sprintf("Successfully removed domain '%s' from /etc/hosts.", *domain))
 }
 
-// writeLinesToFile writes a slice of strings to a file, overwriting its contents
-func writeLinesToFile(lines []string, filePath string) error {
-	// Use a buffer to prepare the new content
-	var buffer bytes.Buffer
-	for _, line := range lines {
-		buffer.WriteString(line + "\n")
-	}
-
-	// Use sudo to write to /etc/hosts without printing the output
-	cmd := exec.Command("sudo", "tee", filePath)
-	cmd.Stdin = &buffer
-	cmd.Stdout = nil // Suppress output to the screen
-	cmd.Stderr = nil // Suppress error output to the screen
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to write to %s: %s", filePath, err.Error())
-	}
-
-	return nil
+// confirmAction prompts the user for confirmation before proceeding
+func confirmAction(domain string) bool {
+	return confirm(fmt.Sprintf("Are you sure you want to delete the domain '%s' and its references in /etc/hosts? (y/N): ", domain))
 }
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/liviu-hariton/localhost/internal/dns"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/system"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func DNSCommand(args []string) {
+	if len(args) < 1 {
+		utils.LogWarning("Please provide a dns subcommand. For example:")
+		fmt.Println("    localhost dns serve -tld=test")
+		fmt.Println("    localhost dns install -tld=test")
+		fmt.Println("    localhost dns uninstall")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "serve":
+		dnsServe(args[1:])
+	case "install":
+		dnsInstall(args[1:])
+	case "uninstall":
+		dnsUninstall(args[1:])
+	default:
+		utils.LogWarning(fmt.Sprintf("Unknown dns subcommand '%s'. Use 'help' for usage information.", args[0]))
+		os.Exit(1)
+	}
+}
+
+// dnsServe runs the built-in DNS responder in the foreground until interrupted
+func dnsServe(args []string) {
+	flagSet := flag.NewFlagSet("dns serve", flag.ExitOnError)
+	var tlds, patterns utils.StringList
+	flagSet.Var(&tlds, "tld", "A TLD to answer for, including all of its subdomains, e.g. test (repeatable)")
+	flagSet.Var(&patterns, "pattern", "A name or wildcard pattern to answer for, e.g. *.shop.local (repeatable)")
+	listen := flagSet.String("listen", "127.0.0.1", "The address to bind to")
+	port := flagSet.Int("port", dns.DefaultPort, "The UDP and TCP port to bind to")
+	flagSet.Parse(args)
+
+	if len(tlds) == 0 && len(patterns) == 0 {
+		utils.LogWarning("Please provide at least one -tld or -pattern flag. For example:")
+		fmt.Println("    localhost dns serve -tld=test -pattern=*.shop.local")
+		os.Exit(1)
+	}
+
+	matcher, err := dns.NewMatcher(tlds, patterns)
+	if err != nil {
+		utils.LogError("Configuring the DNS responder", err)
+		os.Exit(1)
+	}
+
+	server := dns.NewServer(*listen, *port, matcher)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	names := append(append([]string{}, tlds...), patterns...)
+	utils.LogInfo(fmt.Sprintf("Answering for %s on %s (udp and tcp). Press Ctrl+C to stop.", strings.Join(names, ", "), server.Addr))
+
+	if err := server.ListenAndServe(ctx); err != nil {
+		utils.LogError("Running the DNS responder", err)
+		os.Exit(1)
+	}
+
+	utils.LogInfo("DNS responder stopped.")
+}
+
+// dnsInstall points the OS resolver at the built-in DNS responder for the given TLDs
+func dnsInstall(args []string) {
+	flagSet := flag.NewFlagSet("dns install", flag.ExitOnError)
+	var tlds utils.StringList
+	flagSet.Var(&tlds, "tld", "A TLD to send to the built-in DNS responder, e.g. test (repeatable)")
+	listen := flagSet.String("listen", "127.0.0.1", "The address the DNS responder listens on")
+	port := flagSet.Int("port", dns.DefaultPort, "The port the DNS responder listens on")
+	backend := flagSet.String("backend", dns.DefaultResolverBackend(), "The resolver configuration to write: macos (/etc/resolver) or systemd (systemd-resolved drop-in)")
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any files or directories")
+	flagSet.Parse(args)
+
+	if len(tlds) == 0 {
+		utils.LogWarning("Please provide at least one -tld flag. For example:")
+		fmt.Println("    localhost dns install -tld=test")
+		os.Exit(1)
+	}
+
+	utils.SetDryRun(*dryRun)
+
+	cfg := dns.ResolverConfig{Root: paths.Get().Root, Backend: *backend, Host: *listen, Port: *port}
+	for _, tld := range tlds {
+		path, err := dns.InstallResolver(cfg, tld)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Installing the resolver for '%s'", tld), err)
+			os.Exit(1)
+		}
+		fmt.Printf("✔ Queries for '.%s' are sent to %s:%d (%s).\n", tld, *listen, *port, path)
+	}
+
+	applyResolverChanges(paths.Get().Root, *backend == dns.ResolverSystemd)
+
+	utils.LogSuccess(fmt.Sprintf("Resolver configured. Start the responder with: localhost dns serve -tld=%s", strings.Join(tlds, " -tld=")))
+}
+
+// dnsUninstall removes the resolver files created by dns install
+func dnsUninstall(args []string) {
+	flagSet := flag.NewFlagSet("dns uninstall", flag.ExitOnError)
+	var tlds utils.StringList
+	flagSet.Var(&tlds, "tld", "Only remove the resolver for this TLD (repeatable, defaults to all of them)")
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any files or directories")
+	flagSet.Parse(args)
+
+	utils.SetDryRun(*dryRun)
+
+	owned, err := dns.OwnedResolvers(paths.Get().Root)
+	if err != nil {
+		utils.LogError("Finding resolver files", err)
+		os.Exit(1)
+	}
+
+	removed, systemd := 0, false
+	for _, file := range owned {
+		if len(tlds) > 0 && !containsTLD(tlds, file.TLD) {
+			continue
+		}
+
+		if err := dns.UninstallResolver(file); err != nil {
+			utils.LogError(fmt.Sprintf("Removing the resolver for '%s'", file.TLD), err)
+			os.Exit(1)
+		}
+		fmt.Printf("✔ Removed resolver for '.%s' (%s).\n", file.TLD, file.Path)
+
+		removed++
+		systemd = systemd || file.Backend == dns.ResolverSystemd
+	}
+
+	if removed == 0 {
+		utils.LogInfo("No resolver files managed by localhost were found.")
+		return
+	}
+
+	applyResolverChanges(paths.Get().Root, systemd)
+
+	utils.LogSuccess("Resolver configuration removed.")
+}
+
+// applyResolverChanges makes the OS pick up new resolver files, unless they were
+// written under an alternative root
+func applyResolverChanges(root string, systemd bool) {
+	if root != "/" {
+		return
+	}
+
+	if systemd {
+		if err := system.RestartSystemdResolved(); err != nil {
+			utils.LogWarning(err.Error())
+		}
+	}
+
+	if err := system.FlushDNSCache(); err != nil {
+		utils.LogWarning(err.Error())
+	}
+}
+
+// containsTLD reports whether the TLD was requested on the command line
+func containsTLD(tlds []string, tld string) bool {
+	for _, t := range tlds {
+		if strings.EqualFold(strings.Trim(t, "."), tld) {
+			return true
+		}
+	}
+	return false
+}
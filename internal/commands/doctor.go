@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/liviu-hariton/localhost/internal/doctor"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func DoctorCommand(args []string) {
+	utils.LogInfo("Running environment diagnostics (read-only)...")
+
+	results := doctor.Run()
+
+	for _, result := range results {
+		color := utils.ColorGreen
+		switch result.Status {
+		case doctor.Warn:
+			color = utils.ColorYellow
+		case doctor.Fail:
+			color = utils.ColorRed
+		}
+
+		line := fmt.Sprintf("%s[%s]%s %s", color, result.Status, utils.ColorReset, result.Name)
+		if result.Detail != "" {
+			line += ": " + result.Detail
+		}
+		fmt.Println(line)
+
+		if result.Hint != "" && result.Status != doctor.Pass {
+			fmt.Printf("       → %s\n", result.Hint)
+		}
+	}
+
+	if doctor.HasFailures(results) {
+		utils.LogWarning("Some checks failed. Fix them before running 'create'.")
+		os.Exit(1)
+	}
+
+	utils.LogSuccess("Your environment looks good!")
+}
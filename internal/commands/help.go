@@ -8,5 +8,11 @@ func HelpCommand(args []string) {
 	fmt.Println("  create   Create a new local domain configuration")
 	fmt.Println("  list     List all configured local domains")
 	fmt.Println("  delete   Delete an existing local domain configuration")
+	fmt.Println("  doctor   Check the local environment and report problems (read-only)")
+	fmt.Println("  status   Show whether Apache is running, its PID, uptime and listening ports")
+	fmt.Println("  dns      Run the built-in DNS responder and manage resolver files (dns serve|install|uninstall)")
+	fmt.Println("  certs    List the certificates or renew the expiring ones (certs list|renew)")
+	fmt.Println("  ca       Trust the local certificate authority in the OS and browser stores (ca install|uninstall)")
+	fmt.Println("  restore  List or restore backups of the files edited by the tool")
 	fmt.Println("  help     Show this help message")
 }
@@ -3,23 +3,79 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/config"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/system"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
 func ListCommand(args []string) {
-	vhostsDir := "/opt/homebrew/etc/httpd/extra/vhosts/"
-	files, err := os.ReadDir(vhostsDir)
+	files, err := os.ReadDir(paths.Get().VhostsDir)
 	if err != nil {
 		utils.LogError(fmt.Sprintf("Error reading vhosts directory: %s\n", err), err)
 		return
 	}
 
+	hosts, err := config.LoadHostsFile(paths.Get().HostsFile)
+	if err != nil {
+		utils.LogError("Reading hosts file", err)
+		return
+	}
+
 	fmt.Println("Configured domains:")
+
+	// Domains served by a local virtual host
+	listed := map[string]bool{}
 	for _, file := range files {
 		if file.IsDir() || !file.Type().IsRegular() || file.Name() == ".DS_Store" {
 			continue
 		}
-		utils.LogDebug(file.Name())
+
+		domain := strings.TrimSuffix(file.Name(), ".conf")
+		listed[domain] = true
+
+		addresses := hosts.Addresses(domain)
+		if len(addresses) == 0 {
+			utils.LogDebug(fmt.Sprintf("%s (no entry in %s)", file.Name(), paths.Get().HostsFile))
+			continue
+		}
+		utils.LogDebug(fmt.Sprintf("%s -> %s%s", file.Name(), strings.Join(addresses, ", "), formatAliases(hosts.Aliases(domain))))
+	}
+
+	// Domains that only exist in the hosts file, e.g. pointing to a VM or a container
+	for _, domain := range hosts.Domains() {
+		if listed[domain] {
+			continue
+		}
+		utils.LogDebug(fmt.Sprintf("%s -> %s (served elsewhere)%s", domain, strings.Join(hosts.Addresses(domain), ", "), formatAliases(hosts.Aliases(domain))))
+	}
+
+	warnExpiringCertificates()
+}
+
+// warnExpiringCertificates warns about the certificates expiring within the cert_warn_days window
+func warnExpiringCertificates() {
+	window := system.CertificateWarnWindow()
+
+	list, err := system.Certificates()
+	if err != nil {
+		utils.LogWarning(err.Error())
+		return
+	}
+
+	for _, cert := range list {
+		if warning := cert.ExpiryWarning(window); warning != "" {
+			utils.LogWarning(warning)
+		}
+	}
+}
+
+// formatAliases renders the aliases of a domain for the listing
+func formatAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
 	}
+	return fmt.Sprintf(" [aliases: %s]", strings.Join(aliases, ", "))
 }
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// confirm asks a yes/no question and reports whether the user answered yes
+func confirm(prompt string) bool {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print(prompt)
+	response, _ := reader.ReadString('\n')
+
+	// Normalize and trim input
+	response = strings.ToLower(strings.TrimSpace(response))
+
+	return response == "y"
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func RestoreCommand(args []string) {
+	flagSet := flag.NewFlagSet("restore", flag.ExitOnError)
+	at := flagSet.String("at", "", "The timestamp of the backup to restore (a unique prefix or 'latest')")
+	dryRun := flagSet.Bool("dry-run", false, "Simulate changes without modifying any files or directories")
+
+	// Accept the file both before and after the flags
+	var file string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file = args[0]
+		flagSet.Parse(args[1:])
+	} else {
+		flagSet.Parse(args)
+		file = flagSet.Arg(0)
+	}
+
+	if file == "" {
+		utils.LogWarning("Please provide the file to restore. For example:")
+		fmt.Println("    localhost restore /etc/hosts")
+		fmt.Println("    localhost restore /etc/hosts --at 20250110-153000")
+		flagSet.Usage()
+		return
+	}
+
+	utils.SetDryRun(*dryRun)
+
+	// Without a timestamp, only list the available backups
+	if *at == "" {
+		backups, err := utils.ListBackups(file)
+		if err != nil {
+			utils.LogError("Listing backups", err)
+			return
+		}
+
+		if len(backups) == 0 {
+			utils.LogInfo(fmt.Sprintf("No backups found for %s.", file))
+			return
+		}
+
+		fmt.Printf("Backups of %s (oldest first):\n", file)
+		for _, backup := range backups {
+			utils.LogDebug(fmt.Sprintf("%s  %s  %d bytes", backup.Timestamp, backup.Time.Format("2006-01-02 15:04:05"), backup.Size))
+		}
+		fmt.Println("Use --at <timestamp> to restore one of them.")
+		return
+	}
+
+	backup, err := utils.FindBackup(file, *at)
+	if err != nil {
+		utils.LogError("Finding backup", err)
+		os.Exit(1)
+	}
+
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would restore %s from the backup taken at %s.", file, backup.Timestamp))
+		return
+	}
+
+	if !confirmRestore(file, backup.Timestamp) {
+		utils.LogInfo("Restore aborted by user.")
+		return
+	}
+
+	if err := utils.RestoreBackup(file, backup); err != nil {
+		utils.LogError("Restoring backup", err)
+		os.Exit(1)
+	}
+
+	utils.LogSuccess(fmt.Sprintf("Restored %s from the backup taken at %s.", file, backup.Timestamp))
+}
+
+// confirmRestore prompts the user for confirmation before overwriting a file with a backup
+func confirmRestore(file, timestamp string) bool {
+	return confirm(fmt.Sprintf("Are you sure you want to replace %s with the backup taken at %s? (y/N): ", file, timestamp))
+}
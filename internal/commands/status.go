@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/system"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func StatusCommand(args []string) {
+	status, err := system.CheckApacheStatus()
+	if err != nil {
+		utils.LogError("Checking the Apache status", err)
+		os.Exit(1)
+	}
+
+	if status.Running() {
+		fmt.Printf("%sApache is running%s (pid %d, up %s)\n", utils.ColorGreen, utils.ColorReset, status.PID, status.Uptime())
+	} else {
+		fmt.Printf("%sApache is not running%s\n", utils.ColorRed, utils.ColorReset)
+	}
+	fmt.Printf("  PID file: %s\n", status.PidFile)
+
+	var ports []string
+	for _, port := range status.Ports {
+		label := "not accepting connections"
+		if system.ContainsPort(status.Bound, port) {
+			label = "accepting connections"
+		}
+		ports = append(ports, fmt.Sprintf("%d (%s)", port, label))
+	}
+	if len(ports) == 0 {
+		ports = append(ports, "no Listen directive found")
+	}
+	fmt.Printf("  Ports:    %s\n", strings.Join(ports, ", "))
+
+	if !status.Running() {
+		os.Exit(1)
+	}
+}
@@ -1,82 +1,541 @@
 package config
 
 import (
-	"bufio"
 	"fmt"
+	"net/netip"
 	"os"
 	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
-// HostsFilePath defines the path to the hosts file
-const HostsFilePath = "/etc/hosts"
+// Loopback addresses the local domains are mapped to.
+const (
+	LoopbackIPv4 = "127.0.0.1"
+	LoopbackIPv6 = "::1"
+)
 
-// CheckDomainInHosts checks if the domain already exists in the hosts file.
-func CheckDomainInHosts(domain string) (bool, error) {
-	file, err := os.Open(HostsFilePath)
+// LoopbackAddresses returns the loopback addresses for the given address family:
+// "both", "ipv4" or "ipv6".
+func LoopbackAddresses(family string) ([]string, error) {
+	switch family {
+	case "", "both":
+		return []string{LoopbackIPv4, LoopbackIPv6}, nil
+	case "ipv4":
+		return []string{LoopbackIPv4}, nil
+	case "ipv6":
+		return []string{LoopbackIPv6}, nil
+	default:
+		return nil, fmt.Errorf("unknown address family '%s' (expected both, ipv4 or ipv6)", family)
+	}
+}
+
+// ParseAddresses validates the IPv4 and IPv6 addresses a domain should point to
+// and returns them in canonical form. It also reports whether they are loopback
+// addresses; loopback and remote addresses cannot be mixed for a single domain.
+func ParseAddresses(values []string) ([]string, bool, error) {
+	var addresses []string
+	loopback, remote := false, false
+
+	for _, value := range values {
+		addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+		if err != nil || addr.Zone() != "" {
+			return nil, false, fmt.Errorf("'%s' is not a valid IPv4 or IPv6 address", value)
+		}
+
+		addr = addr.Unmap()
+		if addr.IsUnspecified() || addr.IsMulticast() {
+			return nil, false, fmt.Errorf("'%s' cannot be used as a host address", value)
+		}
+
+		if addr.IsLoopback() {
+			loopback = true
+		} else {
+			remote = true
+		}
+
+		if !containsString(addresses, addr.String()) {
+			addresses = append(addresses, addr.String())
+		}
+	}
+
+	if loopback && remote {
+		return nil, false, fmt.Errorf("loopback and remote addresses cannot be mixed for the same domain")
+	}
+
+	return addresses, loopback, nil
+}
+
+// ValidateHostname checks that the value is a plain hostname, e.g.
+// myproject.local: dot-separated labels of letters, digits and hyphens. Domains
+// end up in file paths, so anything else, such as "..", is rejected.
+func ValidateHostname(value string) error {
+	if value == "" || len(value) > 253 {
+		return fmt.Errorf("'%s' is not a valid hostname", value)
+	}
+
+	for _, label := range strings.Split(value, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("'%s' is not a valid hostname", value)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("'%s' is not a valid hostname: '%c' is not allowed", value, r)
+			}
+		}
+	}
+	return nil
+}
+
+// containsHostname reports whether the list holds the hostname, ignoring case
+func containsHostname(hostnames []string, hostname string) bool {
+	for _, name := range hostnames {
+		if strings.EqualFold(name, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether the slice holds the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Markers delimiting the part of the hosts file owned by this tool.
+const (
+	hostsBlockBegin = "# BEGIN localhost"
+	hostsBlockEnd   = "# END localhost"
+)
+
+// HostsEntry is a single line of the hosts file. Address lines carry the parsed
+// IP and hostnames; comments and blank lines only keep their raw text.
+type HostsEntry struct {
+	IP        string
+	Hostnames []string
+	Comment   string
+
+	raw string
+}
+
+// IsAddress reports whether the line maps an IP address to hostnames.
+func (e HostsEntry) IsAddress() bool {
+	return e.IP != ""
+}
+
+// HasHostname reports whether the entry lists the given hostname. Hostnames are
+// compared as whole fields and, like DNS names, case-insensitively.
+func (e HostsEntry) HasHostname(hostname string) bool {
+	return containsHostname(e.Hostnames, hostname)
+}
+
+// hasAnyHostname reports whether the entry lists any of the given hostnames.
+func (e HostsEntry) hasAnyHostname(hostnames []string) bool {
+	for _, hostname := range hostnames {
+		if e.HasHostname(hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutHostnames returns a copy of the entry with the given hostnames removed.
+func (e HostsEntry) withoutHostnames(hostnames []string) HostsEntry {
+	stripped := HostsEntry{IP: e.IP, Comment: e.Comment}
+	for _, name := range e.Hostnames {
+		if !containsHostname(hostnames, name) {
+			stripped.Hostnames = append(stripped.Hostnames, name)
+		}
+	}
+	return stripped
+}
+
+// String returns the line as it should be written to the hosts file.
+func (e HostsEntry) String() string {
+	if e.raw != "" || !e.IsAddress() {
+		return e.raw
+	}
+
+	line := e.IP + " " + strings.Join(e.Hostnames, " ")
+	if e.Comment != "" {
+		line += " # " + e.Comment
+	}
+	return line
+}
+
+// parseHostsLine splits a raw hosts file line into its address, hostnames and comment.
+func parseHostsLine(raw string) HostsEntry {
+	entry := HostsEntry{raw: raw}
+
+	content := raw
+	if i := strings.Index(content, "#"); i >= 0 {
+		entry.Comment = strings.TrimSpace(content[i+1:])
+		content = content[:i]
+	}
+
+	fields := strings.Fields(content)
+	if len(fields) >= 2 {
+		entry.IP = fields[0]
+		entry.Hostnames = fields[1:]
+	}
+
+	return entry
+}
+
+// HostsFile is a parsed hosts file. Lines outside the managed block are kept
+// verbatim so that hand-written entries are written back byte-for-byte.
+type HostsFile struct {
+	path    string
+	before  []HostsEntry
+	managed []HostsEntry
+	after   []HostsEntry
+
+	hasBlock        bool
+	trailingNewline bool
+}
+
+// LoadHostsFile reads and parses the hosts file at the given path.
+func LoadHostsFile(path string) (*HostsFile, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return false, fmt.Errorf("failed to open hosts file: %s", err.Error())
+		return nil, fmt.Errorf("failed to read hosts file: %s", err.Error())
+	}
+
+	hosts, err := ParseHostsFile(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// ParseHostsFile parses hosts file content, splitting it around the managed block.
+func ParseHostsFile(path string, data []byte) (*HostsFile, error) {
+	hosts := &HostsFile{path: path}
+
+	content := string(data)
+	if content == "" {
+		hosts.trailingNewline = true
+		return hosts, nil
+	}
+
+	hosts.trailingNewline = strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+
+	inBlock := false
+	for _, raw := range strings.Split(content, "\n") {
+		marker := strings.TrimSpace(raw)
+
+		switch {
+		case marker == hostsBlockBegin:
+			if hosts.hasBlock {
+				return nil, fmt.Errorf("hosts file contains more than one '%s' block", hostsBlockBegin)
+			}
+			inBlock = true
+			hosts.hasBlock = true
+		case marker == hostsBlockEnd:
+			if !inBlock {
+				return nil, fmt.Errorf("hosts file contains '%s' without a matching '%s'", hostsBlockEnd, hostsBlockBegin)
+			}
+			inBlock = false
+		case inBlock:
+			hosts.managed = append(hosts.managed, parseHostsLine(raw))
+		case hosts.hasBlock:
+			hosts.after = append(hosts.after, parseHostsLine(raw))
+		default:
+			hosts.before = append(hosts.before, parseHostsLine(raw))
+		}
+	}
+
+	if inBlock {
+		return nil, fmt.Errorf("hosts file contains '%s' without a matching '%s'", hostsBlockBegin, hostsBlockEnd)
+	}
+
+	return hosts, nil
+}
+
+// Managed returns the address entries inside the block owned by this tool.
+func (h *HostsFile) Managed() []HostsEntry {
+	var entries []HostsEntry
+	for _, entry := range h.managed {
+		if entry.IsAddress() {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Has reports whether the hostname is mapped anywhere in the hosts file.
+func (h *HostsFile) Has(hostname string) bool {
+	for _, lines := range [][]HostsEntry{h.before, h.managed, h.after} {
+		for _, entry := range lines {
+			if entry.HasHostname(hostname) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasMapping reports whether the hostname is mapped to the given IP anywhere in the hosts file.
+func (h *HostsFile) HasMapping(ip, hostname string) bool {
+	for _, lines := range [][]HostsEntry{h.before, h.managed, h.after} {
+		for _, entry := range lines {
+			if entry.IP == ip && entry.HasHostname(hostname) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Addresses returns the IPs the hostname is mapped to inside the managed block.
+func (h *HostsFile) Addresses(hostname string) []string {
+	var addresses []string
+	for _, entry := range h.Managed() {
+		if entry.HasHostname(hostname) && !containsString(addresses, entry.IP) {
+			addresses = append(addresses, entry.IP)
+		}
+	}
+	return addresses
+}
+
+// Domains returns the primary hostname of each managed entry, in file order. The
+// primary hostname is the first one on the line; the others are its aliases.
+func (h *HostsFile) Domains() []string {
+	var domains []string
+	for _, entry := range h.Managed() {
+		if !containsString(domains, entry.Hostnames[0]) {
+			domains = append(domains, entry.Hostnames[0])
+		}
+	}
+	return domains
+}
+
+// Aliases returns the hostnames listed after the domain on its managed entries.
+func (h *HostsFile) Aliases(domain string) []string {
+	var aliases []string
+	for _, entry := range h.Managed() {
+		if !strings.EqualFold(entry.Hostnames[0], domain) {
+			continue
+		}
+		for _, name := range entry.Hostnames[1:] {
+			if !containsString(aliases, name) {
+				aliases = append(aliases, name)
+			}
+		}
+	}
+	return aliases
+}
+
+// Add appends an entry mapping the IP to the hostnames inside the managed block.
+func (h *HostsFile) Add(ip string, hostnames ...string) {
+	if !h.hasBlock {
+		// Keep a new block visually separated from hand-written entries. The
+		// blank line is always added, so that Remove can always take it out.
+		if len(h.before) > 0 {
+			h.before = append(h.before, HostsEntry{})
+		}
+		h.hasBlock = true
+	}
+
+	h.managed = append(h.managed, HostsEntry{IP: ip, Hostnames: hostnames})
+}
+
+// AddAliases appends the hostnames to the managed entry mapping the IP to the
+// domain, where the domain's aliases are recorded. It reports whether there is
+// such an entry.
+func (h *HostsFile) AddAliases(ip, domain string, hostnames ...string) bool {
+	for i, entry := range h.managed {
+		if entry.IP != ip || len(entry.Hostnames) == 0 || !strings.EqualFold(entry.Hostnames[0], domain) {
+			continue
+		}
+
+		h.managed[i] = HostsEntry{IP: entry.IP, Hostnames: append(append([]string{}, entry.Hostnames...), hostnames...), Comment: entry.Comment}
+		return true
+	}
+	return false
+}
+
+// Remove drops the hostnames from every managed entry that lists them and returns
+// the affected lines as they were before the change. Entries left without any
+// hostname are removed, and so is the block once it holds nothing else; lines
+// outside the managed block, and comments inside it, are never touched.
+func (h *HostsFile) Remove(hostnames ...string) []HostsEntry {
+	var kept, affected []HostsEntry
+	for _, entry := range h.managed {
+		if !entry.hasAnyHostname(hostnames) {
+			kept = append(kept, entry)
+			continue
+		}
+
+		affected = append(affected, entry)
+		if stripped := entry.withoutHostnames(hostnames); len(stripped.Hostnames) > 0 {
+			kept = append(kept, stripped)
+		}
+	}
+
+	h.managed = kept
+	if h.hasBlock && h.blockIsEmpty() {
+		h.managed = nil
+		h.hasBlock = false
+
+		// Take out the blank line Add put before the block at the end of the file
+		if n := len(h.before); n > 0 && len(h.after) == 0 && strings.TrimSpace(h.before[n-1].String()) == "" {
+			h.before = h.before[:n-1]
+		}
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, domain) {
-			return true, nil
+	return affected
+}
+
+// blockIsEmpty reports whether the managed block holds nothing but blank lines.
+func (h *HostsFile) blockIsEmpty() bool {
+	for _, entry := range h.managed {
+		if strings.TrimSpace(entry.String()) != "" {
+			return false
 		}
 	}
+	return true
+}
 
-	if err := scanner.Err(); err != nil {
-		return false, fmt.Errorf("failed to read hosts file: %s", err.Error())
+// Bytes renders the hosts file, writing the managed block between its markers.
+func (h *HostsFile) Bytes() []byte {
+	var lines []string
+	for _, entry := range h.before {
+		lines = append(lines, entry.String())
+	}
+
+	if h.hasBlock {
+		lines = append(lines, hostsBlockBegin)
+		for _, entry := range h.managed {
+			lines = append(lines, entry.String())
+		}
+		lines = append(lines, hostsBlockEnd)
+	}
+
+	for _, entry := range h.after {
+		lines = append(lines, entry.String())
+	}
+
+	// A file without a final newline keeps it that way, with the block or without
+	content := strings.Join(lines, "\n")
+	if h.trailingNewline && len(lines) > 0 {
+		content += "\n"
+	}
+	return []byte(content)
+}
+
+// Save writes the hosts file back to the path it was loaded from.
+func (h *HostsFile) Save() error {
+	if err := utils.WriteFileAtomic(h.path, h.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write hosts file: %s", err.Error())
+	}
+	return nil
+}
+
+// CheckDomainInHosts checks if the domain already exists in the hosts file.
+func CheckDomainInHosts(domain string) (bool, error) {
+	hosts, err := LoadHostsFile(paths.Get().HostsFile)
+	if err != nil {
+		return false, err
 	}
 
-	return false, nil
+	return hosts.Has(domain), nil
 }
 
-// AddDomainToHosts adds the domain to the hosts file if it doesn't already exist.
-func AddDomainToHosts(domain string) error {
+// AddDomainToHosts maps the domain and its aliases to each of the addresses in the
+// managed block of the hosts file, skipping the mappings that already exist. The
+// domain and its aliases share a line, which is how the aliases are recorded.
+func AddDomainToHosts(domain string, aliases []string, addresses []string) error {
 	if utils.IsDryRun() {
-		fmt.Println("DRY RUN: Would add the domain to the hosts file.")
+		fmt.Printf("DRY RUN: Would add the domain to the hosts file for %s.\n", strings.Join(addresses, ", "))
 		return nil
 	}
 
-	exists, err := CheckDomainInHosts(domain)
+	hosts, err := LoadHostsFile(paths.Get().HostsFile)
 	if err != nil {
 		return err
 	}
 
-	if exists {
-		fmt.Printf("✔ The domain '%s' already exists in the hosts file.\n", domain)
+	added := 0
+	for _, ip := range addresses {
+		var hostnames []string
+		for _, name := range append([]string{domain}, aliases...) {
+			if hosts.HasMapping(ip, name) {
+				fmt.Printf("✔ The domain '%s' already exists in the hosts file for %s.\n", name, ip)
+				continue
+			}
+			hostnames = append(hostnames, name)
+		}
+
+		if len(hostnames) == 0 {
+			continue
+		}
+		added++
+
+		// New aliases of a domain already mapped join its line
+		if hosts.AddAliases(ip, domain, hostnames...) {
+			continue
+		}
+		if !containsHostname(hostnames, domain) {
+			hostnames = append([]string{domain}, hostnames...)
+		}
+		hosts.Add(ip, hostnames...)
+	}
+
+	if added == 0 {
 		return nil
 	}
 
-	// Open the hosts file for appending
-	file, err := os.OpenFile(HostsFilePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err := hosts.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✔ Successfully added '%s' to the hosts file.\n", strings.Join(append([]string{domain}, aliases...), "', '"))
+	return nil
+}
+
+// RemoveDomainFromHosts removes the domain and its recorded aliases from the managed
+// block of the hosts file.
+func RemoveDomainFromHosts(domain string) error {
+	hosts, err := LoadHostsFile(paths.Get().HostsFile)
 	if err != nil {
-		if os.IsPermission(err) {
-			return fmt.Errorf("permission denied: you must run this program with elevated permissions (e.g., using sudo)")
+		return err
+	}
+
+	hostnames := append([]string{domain}, hosts.Aliases(domain)...)
+	affected := hosts.Remove(hostnames...)
+	if len(affected) == 0 {
+		if hosts.Has(domain) {
+			utils.LogWarning(fmt.Sprintf("'%s' is listed in %s outside the block managed by localhost. Leaving it untouched.", domain, paths.Get().HostsFile))
+		} else {
+			utils.LogInfo(fmt.Sprintf("No entries for '%s' found in %s.", domain, paths.Get().HostsFile))
 		}
-		return fmt.Errorf("failed to open hosts file for writing: %s", err.Error())
+		return nil
 	}
-	defer file.Close()
 
-	// Add the new domain
-	newEntry := fmt.Sprintf("\n127.0.0.1 %s\n", domain)
-	if _, err := file.WriteString(newEntry); err != nil {
-		return fmt.Errorf("failed to write to hosts file: %s", err.Error())
+	for _, entry := range affected {
+		if len(entry.withoutHostnames(hostnames).Hostnames) == 0 {
+			utils.LogInfo(fmt.Sprintf("Removing line from %s: %s", paths.Get().HostsFile, entry.String()))
+		} else {
+			utils.LogInfo(fmt.Sprintf("Removing '%s' from line in %s: %s", strings.Join(hostnames, "', '"), paths.Get().HostsFile, entry.String()))
+		}
 	}
 
-	fmt.Printf("✔ Successfully added '%s' to the hosts file.\n", domain)
-	return nil
+	return hosts.Save()
 }
 
 // ModifyHosts handles the entire process of adding a domain to the hosts file.
-func ModifyHosts(domain string) error {
+func ModifyHosts(domain string, aliases []string, addresses []string) error {
 	fmt.Printf("Modifying hosts file for domain: %s\n", domain)
 
-	err := AddDomainToHosts(domain)
+	err := AddDomainToHosts(domain, aliases, addresses)
 	if err != nil {
 		return fmt.Errorf("failed to modify hosts file: %s", err.Error())
 	}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func TestHostsAddRemoveRestoresFile(t *testing.T) {
+	for _, original := range []string{
+		"",
+		"127.0.0.1 localhost",
+		"127.0.0.1 localhost\n",
+		"127.0.0.1 localhost\n\n",
+		"# hosts\n127.0.0.1 localhost\n::1 localhost\n",
+	} {
+		hosts, err := ParseHostsFile("hosts", []byte(original))
+		if err != nil {
+			t.Fatalf("%q: %v", original, err)
+		}
+		hosts.Add(LoopbackIPv4, "myproject.local", "www.myproject.local")
+		hosts.Add(LoopbackIPv6, "myproject.local", "www.myproject.local")
+		created := hosts.Bytes()
+
+		// As create and delete do, each loading the file written by the previous command
+		hosts, err = ParseHostsFile("hosts", created)
+		if err != nil {
+			t.Fatalf("%q: parsing the file with the block: %v", original, err)
+		}
+		if !hosts.HasMapping(LoopbackIPv6, "www.myproject.local") {
+			t.Errorf("%q: the block lost an entry:\n%s", original, created)
+		}
+		if affected := hosts.Remove("myproject.local", "www.myproject.local"); len(affected) != 2 {
+			t.Errorf("%q: Remove affected %d lines, want 2", original, len(affected))
+		}
+
+		if got := string(hosts.Bytes()); got != original {
+			t.Errorf("%q: after adding and removing the domain the file is %q", original, got)
+		}
+	}
+}
+
+func TestHostsRemoveKeepsBlockWithOtherDomains(t *testing.T) {
+	original := "127.0.0.1 localhost\n"
+
+	hosts, err := ParseHostsFile("hosts", []byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts.Add(LoopbackIPv4, "one.local")
+	hosts.Add(LoopbackIPv4, "two.local")
+	hosts.Remove("one.local")
+
+	want := "127.0.0.1 localhost\n\n# BEGIN localhost\n127.0.0.1 two.local\n# END localhost\n"
+	if got := string(hosts.Bytes()); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+
+	hosts.Remove("two.local")
+	if got := string(hosts.Bytes()); got != original {
+		t.Errorf("file = %q, want %q", got, original)
+	}
+}
+
+func TestHostsRemoveLeavesLinesAfterBlock(t *testing.T) {
+	// A hand-written line after the block keeps the blank line before it
+	original := "127.0.0.1 localhost\n\n# BEGIN localhost\n127.0.0.1 one.local\n# END localhost\n10.0.0.5 nas\n"
+
+	hosts, err := ParseHostsFile("hosts", []byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts.Remove("one.local")
+
+	want := "127.0.0.1 localhost\n\n10.0.0.5 nas\n"
+	if got := string(hosts.Bytes()); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+}
+
+func TestHostsRemoveKeepsCommentsInBlock(t *testing.T) {
+	original := "127.0.0.1 localhost\n\n# BEGIN localhost\n# Projects of the team\n127.0.0.1 one.local\n\n127.0.0.1 two.local\n# END localhost\n"
+
+	hosts, err := ParseHostsFile("hosts", []byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts.Remove("one.local")
+	hosts.Remove("two.local")
+
+	// The comment was written by hand: the block stays to hold it
+	want := "127.0.0.1 localhost\n\n# BEGIN localhost\n# Projects of the team\n\n# END localhost\n"
+	if got := string(hosts.Bytes()); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+
+	// Adding a domain again reuses the block
+	hosts.Add(LoopbackIPv4, "three.local")
+	want = "127.0.0.1 localhost\n\n# BEGIN localhost\n# Projects of the team\n\n127.0.0.1 three.local\n# END localhost\n"
+	if got := string(hosts.Bytes()); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+}
+
+func TestAddDomainToHostsAppendsAliases(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+	utils.BackupDir = t.TempDir()
+
+	hostsFile := paths.Get().HostsFile
+	if err := os.MkdirAll(filepath.Dir(hostsFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hostsFile, []byte("127.0.0.1 localhost\n127.0.0.1 shop.local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	both := []string{LoopbackIPv4, LoopbackIPv6}
+	steps := []struct {
+		domain  string
+		aliases []string
+	}{
+		{"myproject.local", nil},
+		{"myproject.local", []string{"api.myproject.local"}},
+		{"myproject.local", []string{"api.myproject.local", "www.myproject.local"}},
+		// Mapped by hand outside the block: the alias is recorded under its domain
+		{"shop.local", []string{"admin.shop.local"}},
+	}
+	for _, step := range steps {
+		if err := AddDomainToHosts(step.domain, step.aliases, both); err != nil {
+			t.Fatalf("AddDomainToHosts(%s, %v): %v", step.domain, step.aliases, err)
+		}
+	}
+
+	data, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "127.0.0.1 localhost\n127.0.0.1 shop.local\n\n# BEGIN localhost\n" +
+		"127.0.0.1 myproject.local api.myproject.local www.myproject.local\n" +
+		"::1 myproject.local api.myproject.local www.myproject.local\n" +
+		"127.0.0.1 shop.local admin.shop.local\n" +
+		"::1 shop.local admin.shop.local\n" +
+		"# END localhost\n"
+	if string(data) != want {
+		t.Errorf("hosts file:\n%s\nwant:\n%s", data, want)
+	}
+
+	hosts, err := ParseHostsFile(hostsFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domains := hosts.Domains(); len(domains) != 2 || domains[0] != "myproject.local" || domains[1] != "shop.local" {
+		t.Errorf("Domains = %v, want [myproject.local shop.local]", domains)
+	}
+	if aliases := hosts.Aliases("myproject.local"); len(aliases) != 2 || aliases[0] != "api.myproject.local" || aliases[1] != "www.myproject.local" {
+		t.Errorf("Aliases = %v, want [api.myproject.local www.myproject.local]", aliases)
+	}
+}
+
+func TestHostsMatchExactNames(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+	utils.BackupDir = t.TempDir()
+
+	// Names that contain myproject.local, or that it is a prefix of, in and outside the block
+	original := "127.0.0.1 localhost\n" +
+		"127.0.0.1 api.myproject.local myproject.local.bak\n" +
+		"\n# BEGIN localhost\n" +
+		"127.0.0.1 api.myproject.local\n" +
+		"127.0.0.1 myproject.local.bak\n" +
+		"::1 api.myproject.local myproject.local.bak\n" +
+		"# END localhost\n" +
+		"10.0.0.5 myproject.local.bak\n"
+
+	hostsFile := paths.Get().HostsFile
+	if err := os.MkdirAll(filepath.Dir(hostsFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hostsFile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := CheckDomainInHosts("myproject.local"); err != nil || exists {
+		t.Errorf("CheckDomainInHosts = %v, %v; want false", exists, err)
+	}
+
+	if err := AddDomainToHosts("myproject.local", nil, []string{LoopbackIPv4, LoopbackIPv6}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(original, "# END localhost\n", "127.0.0.1 myproject.local\n::1 myproject.local\n# END localhost\n", 1)
+	if string(data) != want {
+		t.Errorf("after adding, hosts file:\n%s\nwant:\n%s", data, want)
+	}
+
+	if err := RemoveDomainFromHosts("myproject.local"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(hostsFile); string(data) != original {
+		t.Errorf("after removing, hosts file:\n%s\nwant:\n%s", data, original)
+	}
+
+	// Removing a name that is only a part of others changes nothing
+	hosts, err := ParseHostsFile(hostsFile, []byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected := hosts.Remove("myproject.local"); len(affected) != 0 {
+		t.Errorf("Remove affected %v", affected)
+	}
+	if aliases := hosts.Aliases("myproject.local"); len(aliases) != 0 {
+		t.Errorf("Aliases = %v, want none", aliases)
+	}
+}
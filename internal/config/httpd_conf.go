@@ -1,16 +1,30 @@
 package config
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
-// HttpdConfPath defines the path to the Apache main configuration file.
-const HttpdConfPath = "/opt/homebrew/etc/httpd/httpd.conf"
+// vhostsIncludePattern returns the pattern httpd.conf includes to load every file in the vhosts directory.
+func vhostsIncludePattern() string {
+	return filepath.Join(paths.Get().VhostsDir, "*.conf")
+}
+
+// CheckVhostsEnabled reports whether httpd.conf includes the vhosts wildcard line.
+func CheckVhostsEnabled() (bool, error) {
+	conf, err := apacheconf.Load(paths.Get().HttpdConf)
+	if err != nil {
+		return false, err
+	}
+
+	return conf.HasInclude(vhostsIncludePattern()), nil
+}
 
 // EnsureVhostsEnabled ensures that the httpd.conf file includes the vhosts file.
 func EnsureVhostsEnabled() error {
@@ -19,78 +33,51 @@ func EnsureVhostsEnabled() error {
 		return nil
 	}
 
-	file, err := os.Open(HttpdConfPath)
+	conf, err := apacheconf.Load(paths.Get().HttpdConf)
 	if err != nil {
-		return utils.LogError("Opening httpd.conf", err)
-	}
-	defer file.Close()
-
-	var lines []string
-	includeVhostsWildcard := false
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Check if the vhosts wildcard line already exists
-		if strings.Contains(line, "Include /opt/homebrew/etc/httpd/extra/vhosts/*.conf") {
-			includeVhostsWildcard = true
-		}
-
-		lines = append(lines, line)
+		return utils.LogError("Reading httpd.conf", err)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return utils.LogError("Reading httpd.conf", err)
+	// Add the wildcard line, or uncomment it, if it doesn't exist
+	if !conf.AddInclude(vhostsIncludePattern()) {
+		fmt.Println("✔ Virtual hosts wildcard line already exists in httpd.conf.")
+		return nil
 	}
 
-	// If the wildcard line doesn't exist, add it after the default vhosts line
-	if !includeVhostsWildcard {
-		if utils.IsDryRun() {
-			fmt.Println("DRY RUN: Would add 'Include /opt/homebrew/etc/httpd/extra/vhosts/*.conf' to httpd.conf.")
-		} else {
-			lines = append(lines, "Include /opt/homebrew/etc/httpd/extra/vhosts/*.conf")
-			fmt.Println("✔ Added 'Include /opt/homebrew/etc/httpd/extra/vhosts/*.conf' to httpd.conf.")
-		}
+	if err := conf.Save(); err != nil {
+		return utils.LogError("Writing to httpd.conf", err)
 	}
 
-	// Write the updated content back to httpd.conf
-	if !utils.IsDryRun() {
-		file, err = os.OpenFile(HttpdConfPath, os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return utils.LogError("Opening httpd.conf for writing", err)
-		}
-		defer file.Close()
+	fmt.Printf("✔ Added 'Include %s' to httpd.conf.\n", vhostsIncludePattern())
+	return nil
+}
 
-		for _, line := range lines {
-			if _, err := file.WriteString(line + "\n"); err != nil {
-				return utils.LogError("Writing to httpd.conf", err)
-			}
+// vhostAddresses formats the addresses a virtual host listens on for the given port,
+// e.g. "127.0.0.1:80 [::1]:80".
+func vhostAddresses(addresses []string, port int) string {
+	var listen []string
+	for _, ip := range addresses {
+		if strings.Contains(ip, ":") {
+			ip = "[" + ip + "]"
 		}
-	} else {
-		fmt.Println("DRY RUN: Would update httpd.conf with new content.")
-	}
-
-	if !includeVhostsWildcard {
-		fmt.Println("✔ Virtual hosts wildcard line added to httpd.conf.")
-	} else {
-		fmt.Println("✔ Virtual hosts wildcard line already exists in httpd.conf.")
+		listen = append(listen, fmt.Sprintf("%s:%d", ip, port))
 	}
-	return nil
+	return strings.Join(listen, " ")
 }
 
-// AddVirtualHost creates a new virtual host configuration for the domain.
-func AddVirtualHost(domain, documentRoot string) error {
+// AddVirtualHost renders the named vhost template with the site data and writes
+// the resulting virtual host configuration for the domain. The files and
+// directories it creates are journaled, see utils.RevertChanges.
+func AddVirtualHost(data VhostData, templateName string) error {
 	// Define the path for the new vhost config file
-	vhostsDir := "/opt/homebrew/etc/httpd/extra/vhosts/"
-	vhostFile := vhostsDir + domain + ".conf"
+	vhostsDir := paths.Get().VhostsDir
+	vhostFile := paths.Get().VhostFile(data.Domain)
 
-	// Derive log paths based on the document root
-	baseLogDir := fmt.Sprintf("%s/_logs/%s", documentRoot, domain)
-	errorLogDir := fmt.Sprintf("%s/error_log", baseLogDir)
-	accessLogDir := fmt.Sprintf("%s/access_log", baseLogDir)
-	sslErrorLogDir := fmt.Sprintf("%s/ssl/error_log", baseLogDir)
-	sslAccessLogDir := fmt.Sprintf("%s/ssl/access_log", baseLogDir)
+	// Render first, so that a broken template fails before anything is created
+	vhostConfig, err := RenderVhost(templateName, data)
+	if err != nil {
+		return utils.LogError("Rendering virtual host template", err)
+	}
 
 	// Ensure the vhosts directory exists
 	if err := utils.CreateDirectory(vhostsDir); err != nil {
@@ -98,98 +85,47 @@ func AddVirtualHost(domain, documentRoot string) error {
 	}
 
 	// Ensure the log directories exist
-	if err := utils.CreateDirectory(fmt.Sprintf("%s/ssl", baseLogDir)); err != nil {
-		return utils.LogError("Creating log directories", err)
+	for _, logFile := range []string{data.Logs.Error, data.Logs.Access, data.Logs.SSLError, data.Logs.SSLAccess} {
+		if err := utils.CreateDirectory(filepath.Dir(logFile)); err != nil {
+			return utils.LogError("Creating log directories", err)
+		}
 	}
 
-	// Ensure the public directory exists
-	publicDir := fmt.Sprintf("%s/public", documentRoot)
-	if err := utils.CreateDirectory(publicDir); err != nil {
-		rollback(vhostFile, publicDir)
-		return utils.LogError("Creating public directory", err)
+	// Ensure the public directory exists; an existing one belongs to the project
+	// and is never removed on revert. Reverse proxy sites have none.
+	publicDir := data.PublicDir
+	if publicDir != "" {
+		if err := utils.CreateDirectory(publicDir); err != nil {
+			return utils.LogError("Creating public directory", err)
+		}
 	}
 
-	// Write the dummy index.php file
-	if !utils.IsDryRun() {
-		indexPhpFile := fmt.Sprintf("%s/index.php", publicDir)
-		indexPhpContent := fmt.Sprintf("<?php\necho 'It worked! You are on %s domain.';\n", domain)
+	// Write the placeholder page, leaving an existing one alone
+	if publicDir != "" && data.Scaffold.Name != "" {
+		scaffoldFile := filepath.Join(publicDir, data.Scaffold.Name)
 
-		if err := os.WriteFile(indexPhpFile, []byte(indexPhpContent), 0644); err != nil {
-			return utils.LogError("Writing index.php file", err)
+		if _, err := os.Stat(scaffoldFile); err == nil {
+			fmt.Printf("✔ Keeping the existing '%s'.\n", scaffoldFile)
+		} else if !utils.IsDryRun() {
+			if err := utils.WriteFileAtomic(scaffoldFile, []byte(data.Scaffold.Content), 0644); err != nil {
+				return utils.LogError(fmt.Sprintf("Writing %s file", data.Scaffold.Name), err)
+			}
+			fmt.Printf("✔ Dummy %s file created at '%s'.\n", data.Scaffold.Name, scaffoldFile)
+		} else {
+			fmt.Printf("DRY RUN: Would write the dummy %s file.\n", data.Scaffold.Name)
 		}
-		fmt.Printf("✔ Dummy index.php file created at '%s'.\n", indexPhpFile)
-	} else {
-		fmt.Println("DRY RUN: Would write the dummy index.php file.")
 	}
 
 	// Write the configuration to the file
 	if !utils.IsDryRun() {
-		// Open the file for writing
-		file, err := os.OpenFile(vhostFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			rollback(vhostFile, publicDir)
-			return utils.LogError(fmt.Sprintf("Creating vhost file '%s'", vhostFile), err)
-		}
-		defer file.Close()
-
-		// Virtual host configuration template
-		vhostConfig := fmt.Sprintf(`
-<VirtualHost %s:80>
-    ServerName %s
-    DocumentRoot "%s/public"
-    ErrorLog "%s"
-    CustomLog "%s" common
-
-    <Directory "%s">
-        Options FollowSymLinks Multiviews Indexes
-        MultiviewsMatch Any
-        AllowOverride All
-        Require all granted
-    </Directory>
-</VirtualHost>
-
-<VirtualHost %s:443>
-    ServerName %s
-    DocumentRoot "%s/public"
-    SSLEngine on
-    SSLCipherSuite ALL:!ADH:!EXPORT56:RC4+RSA:+HIGH:+MEDIUM:+LOW:+SSLv2:+EXP:+eNULL
-    SSLCertificateFile /opt/homebrew/etc/httpd/ssl/server.crt
-    SSLCertificateKeyFile /opt/homebrew/etc/httpd/ssl/server.key
-    ErrorLog "%s"
-    CustomLog "%s" common
-
-    <Directory "%s">
-        Options FollowSymLinks Multiviews Indexes
-        MultiviewsMatch Any
-        AllowOverride All
-        Require all granted
-    </Directory>
-</VirtualHost>
-`, domain, domain, documentRoot, errorLogDir, accessLogDir, documentRoot, domain, domain, documentRoot, sslErrorLogDir, sslAccessLogDir, documentRoot)
-
-		if _, err := file.WriteString(vhostConfig); err != nil {
-			rollback(vhostFile, publicDir)
+		if err := utils.WriteFileAtomic(vhostFile, vhostConfig, 0644); err != nil {
 			return utils.LogError(fmt.Sprintf("Writing to vhost file '%s'", vhostFile), err)
 		}
 
-		fmt.Printf("✔ Virtual host configuration for '%s' created at '%s'.\n", domain, vhostFile)
+		fmt.Printf("✔ Virtual host configuration for '%s' created at '%s'.\n", data.Domain, vhostFile)
 	} else {
 		fmt.Println("DRY RUN: Would write the virtual host configuration file.")
 	}
 
 	return nil
 }
-
-func rollback(vhostFile, publicDir string) {
-	fmt.Println("Rolling back changes...")
-
-	// Remove the vhost file
-	if err := os.Remove(vhostFile); err == nil {
-		fmt.Printf("✔ Removed partial vhost file: %s\n", vhostFile)
-	}
-
-	// Remove the public directory
-	if err := os.RemoveAll(publicDir); err == nil {
-		fmt.Printf("✔ Removed partial public directory: %s\n", publicDir)
-	}
-}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultTLSProfile is the TLS profile of the sites created without -tls.
+const DefaultTLSProfile = "intermediate"
+
+// tlsProfiles are the Mozilla server side TLS recommendations for Apache
+// (https://wiki.mozilla.org/Security/Server_Side_TLS), version 5.7.
+var tlsProfiles = map[string]VhostTLS{
+	// TLS 1.3 only, for clients from 2019 on
+	"modern": {
+		Profile:          "modern",
+		Protocol:         "-all +TLSv1.3",
+		CipherSuite:      "TLSv1.3 TLS_AES_128_GCM_SHA256:TLS_AES_256_GCM_SHA384:TLS_CHACHA20_POLY1305_SHA256",
+		HonorCipherOrder: "off",
+	},
+	// TLS 1.2 and 1.3 with forward secret AEAD ciphers, the general purpose recommendation
+	"intermediate": {
+		Profile:          "intermediate",
+		Protocol:         "-all +TLSv1.2 +TLSv1.3",
+		CipherSuite:      "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:ECDHE-ECDSA-CHACHA20-POLY1305:ECDHE-RSA-CHACHA20-POLY1305:DHE-RSA-AES128-GCM-SHA256:DHE-RSA-AES256-GCM-SHA384:DHE-RSA-CHACHA20-POLY1305",
+		HonorCipherOrder: "off",
+	},
+}
+
+// TLSProfile returns the TLS profile with the given name.
+func TLSProfile(name string) (VhostTLS, error) {
+	profile, ok := tlsProfiles[strings.ToLower(name)]
+	if !ok {
+		return VhostTLS{}, fmt.Errorf("unknown TLS profile '%s' (available: %s)", name, strings.Join(TLSProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// TLSProfileNames returns the names of the TLS profiles.
+func TLSProfileNames() []string {
+	var names []string
+	for name := range tlsProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+)
+
+// DefaultTemplate is the name of the vhost template used when none is chosen.
+const DefaultTemplate = "default"
+
+// ProxyTemplate is the vhost template used for reverse proxy sites.
+const ProxyTemplate = "proxy"
+
+// templateExt is the extension of vhost template files, both built-in and user-provided.
+const templateExt = ".conf.tmpl"
+
+//go:embed templates/*.conf.tmpl
+var builtinTemplates embed.FS
+
+// VhostPorts are the ports the virtual host listens on.
+type VhostPorts struct {
+	HTTP  int
+	HTTPS int
+}
+
+// VhostLogs are the log files of the virtual host.
+type VhostLogs struct {
+	Error     string
+	Access    string
+	SSLError  string
+	SSLAccess string
+}
+
+// VhostCert is the certificate served on the HTTPS port.
+type VhostCert struct {
+	File    string
+	KeyFile string
+}
+
+// VhostTLS is the TLS profile of the HTTPS port: the protocols, ciphers and
+// cipher order mod_ssl negotiates.
+type VhostTLS struct {
+	Profile          string
+	Protocol         string
+	CipherSuite      string
+	HonorCipherOrder string
+}
+
+// VhostPHPFPM is the PHP-FPM service a site hands its PHP files to, instead of
+// the PHP module loaded in httpd.conf.
+type VhostPHPFPM struct {
+	Version string
+	Socket  string
+}
+
+// VhostScaffold is a placeholder page written to the web root of a new site.
+type VhostScaffold struct {
+	Name    string // File name, relative to the public directory
+	Content string
+}
+
+// VhostData is the data model vhost templates are rendered with.
+type VhostData struct {
+	Domain       string
+	Aliases      []string
+	DocumentRoot string // The project directory
+	PublicDir    string // The directory served as the web root
+	Addresses    []string
+	Ports        VhostPorts
+	Logs         VhostLogs
+	Cert         VhostCert
+	TLS          VhostTLS
+	HTTP2        bool          // Whether the HTTPS port offers HTTP/2
+	Directives   []string      // Extra directives for the served directory
+	ProxyURL     string        // The backend requests are forwarded to, for reverse proxy sites
+	PHPFPM       VhostPHPFPM   // Empty when the site uses the global PHP module
+	Scaffold     VhostScaffold // Not used by templates; written when the site is created
+}
+
+// NewVhostData returns the data for a site with the default public directory,
+// log locations, ports, certificate and TLS profile.
+func NewVhostData(domain string, aliases []string, documentRoot string, addresses []string) VhostData {
+	// Derive log paths based on the document root
+	baseLogDir := fmt.Sprintf("%s/_logs/%s", documentRoot, domain)
+	if documentRoot == "" {
+		baseLogDir = filepath.Join(paths.Get().LogDir, domain)
+	}
+
+	publicDir := ""
+	if documentRoot != "" {
+		publicDir = fmt.Sprintf("%s/public", documentRoot)
+	}
+
+	return VhostData{
+		Domain:       domain,
+		Aliases:      aliases,
+		DocumentRoot: documentRoot,
+		PublicDir:    publicDir,
+		Addresses:    addresses,
+		Ports:        VhostPorts{HTTP: 80, HTTPS: 443},
+		Logs: VhostLogs{
+			Error:     fmt.Sprintf("%s/error_log", baseLogDir),
+			Access:    fmt.Sprintf("%s/access_log", baseLogDir),
+			SSLError:  fmt.Sprintf("%s/ssl/error_log", baseLogDir),
+			SSLAccess: fmt.Sprintf("%s/ssl/access_log", baseLogDir),
+		},
+		Cert: VhostCert{
+			File:    paths.Get().SiteCertificateFile(domain),
+			KeyFile: paths.Get().SiteCertificateKeyFile(domain),
+		},
+		TLS: tlsProfiles[DefaultTLSProfile],
+	}
+}
+
+// ParseProxyURL validates the backend of a reverse proxy site and returns it
+// with the trailing slash ProxyPass expects.
+func ParseProxyURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid proxy URL '%s': %s", value, err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid proxy URL '%s': expected http://host:port or https://host:port", value)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid proxy URL '%s': query strings and fragments are not supported", value)
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u.String(), nil
+}
+
+// templateFuncs are the helpers available to vhost templates.
+var templateFuncs = template.FuncMap{
+	"listen":    vhostAddresses,
+	"join":      strings.Join,
+	"hasPrefix": strings.HasPrefix,
+}
+
+// LoadVhostTemplate returns the named vhost template. A file with the same name
+// in the user template directory overrides the built-in one.
+func LoadVhostTemplate(name string) (*template.Template, error) {
+	if name == "" {
+		name = DefaultTemplate
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name '%s'", name)
+	}
+
+	fileName := name + templateExt
+	tmpl := template.New(fileName).Funcs(templateFuncs).Option("missingkey=error")
+
+	if dir := paths.Get().TemplatesDir; dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err == nil {
+			parsed, err := tmpl.Parse(string(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse template %s: %s", filepath.Join(dir, fileName), err.Error())
+			}
+			return parsed, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read template %s: %s", filepath.Join(dir, fileName), err.Error())
+		}
+	}
+
+	data, err := builtinTemplates.ReadFile("templates/" + fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown template '%s' (available: %s)", name, strings.Join(ListVhostTemplates(), ", "))
+	}
+
+	parsed, err := tmpl.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in template '%s': %s", name, err.Error())
+	}
+	return parsed, nil
+}
+
+// ListVhostTemplates returns the names of the built-in and user templates.
+func ListVhostTemplates() []string {
+	var names []string
+	add := func(fileName string) {
+		name := strings.TrimSuffix(fileName, templateExt)
+		if strings.HasSuffix(fileName, templateExt) && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	if entries, err := fs.ReadDir(builtinTemplates, "templates"); err == nil {
+		for _, entry := range entries {
+			add(entry.Name())
+		}
+	}
+
+	if dir := paths.Get().TemplatesDir; dir != "" {
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				add(entry.Name())
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// RenderVhost renders the named template with the site data.
+func RenderVhost(name string, data VhostData) ([]byte, error) {
+	tmpl, err := LoadVhostTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render template '%s': %s", name, err.Error())
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+)
+
+// Vhost is a virtual host configuration file found in the vhosts directory.
+type Vhost struct {
+	Domain        string
+	Path          string
+	DocumentRoots []string
+}
+
+// ListVhosts returns the virtual host files in the vhosts directory along with
+// the document roots they serve.
+func ListVhosts() ([]Vhost, error) {
+	files, err := os.ReadDir(paths.Get().VhostsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vhosts directory: %s", err.Error())
+	}
+
+	var vhosts []Vhost
+	for _, file := range files {
+		if file.IsDir() || !file.Type().IsRegular() || !strings.HasSuffix(file.Name(), ".conf") {
+			continue
+		}
+
+		path := filepath.Join(paths.Get().VhostsDir, file.Name())
+		roots, err := readDocumentRoots(path)
+		if err != nil {
+			return nil, err
+		}
+
+		vhosts = append(vhosts, Vhost{
+			Domain:        strings.TrimSuffix(file.Name(), ".conf"),
+			Path:          path,
+			DocumentRoots: roots,
+		})
+	}
+
+	return vhosts, nil
+}
+
+// readDocumentRoots returns the distinct DocumentRoot values of a vhost file.
+func readDocumentRoots(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", path, err.Error())
+	}
+	defer file.Close()
+
+	var roots []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "DocumentRoot") {
+			continue
+		}
+
+		root := strings.Trim(strings.Join(fields[1:], " "), `"`)
+		if !containsString(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+
+	return roots, nil
+}
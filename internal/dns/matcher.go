@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Matcher decides which names the responder is authoritative for. Patterns are
+// either exact names ("shop.test") or wildcards ("*.shop.test") that match any
+// name below the given suffix, however deep.
+type Matcher struct {
+	exact    map[string]bool
+	suffixes []string
+}
+
+// NewMatcher builds a matcher from TLDs and patterns. Each TLD matches itself
+// and every name below it, so "test" answers for "test" and "*.test".
+func NewMatcher(tlds, patterns []string) (*Matcher, error) {
+	m := &Matcher{exact: map[string]bool{}}
+
+	for _, tld := range tlds {
+		tld = normalizeName(tld)
+		if tld == "" || strings.Contains(tld, "*") {
+			return nil, fmt.Errorf("invalid TLD '%s'", tld)
+		}
+		m.exact[tld] = true
+		m.suffixes = append(m.suffixes, "."+tld)
+	}
+
+	for _, pattern := range patterns {
+		pattern = normalizeName(pattern)
+		switch {
+		case strings.HasPrefix(pattern, "*.") && !strings.Contains(pattern[2:], "*") && len(pattern) > 2:
+			m.suffixes = append(m.suffixes, pattern[1:])
+		case pattern != "" && !strings.Contains(pattern, "*"):
+			m.exact[pattern] = true
+		default:
+			return nil, fmt.Errorf("invalid pattern '%s' (expected a name or '*.suffix')", pattern)
+		}
+	}
+
+	if len(m.exact) == 0 && len(m.suffixes) == 0 {
+		return nil, fmt.Errorf("at least one TLD or pattern is required")
+	}
+
+	return m, nil
+}
+
+// Match reports whether the responder should answer for the name.
+func (m *Matcher) Match(name string) bool {
+	name = normalizeName(name)
+	if m.exact[name] {
+		return true
+	}
+
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeName lowercases a name and strips its trailing dot.
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
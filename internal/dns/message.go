@@ -0,0 +1,175 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// Record types and classes handled by the responder.
+const (
+	TypeA    uint16 = 1
+	TypeAAAA uint16 = 28
+	TypeANY  uint16 = 255
+
+	ClassIN uint16 = 1
+)
+
+// Response codes.
+const (
+	RcodeSuccess        = 0
+	RcodeFormatError    = 1
+	RcodeServerFailure  = 2
+	RcodeNotImplemented = 4
+	RcodeRefused        = 5
+)
+
+const headerSize = 12
+
+var errMalformed = errors.New("malformed DNS message")
+
+// Question is the single question of a DNS query.
+type Question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// Query is the part of an incoming message the responder needs to answer it.
+type Query struct {
+	ID       uint16
+	Opcode   uint8
+	Recursed bool
+	Question Question
+
+	// questionBytes is the raw question section, echoed back in the response
+	questionBytes []byte
+}
+
+// Answer is a resource record in the answer section of a response.
+type Answer struct {
+	Type uint16
+	TTL  uint32
+	Data []byte
+}
+
+// ParseQuery decodes the header and the question of a DNS query.
+func ParseQuery(msg []byte) (*Query, error) {
+	if len(msg) < headerSize {
+		return nil, errMalformed
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:4])
+	if flags&0x8000 != 0 {
+		return nil, errors.New("message is a response, not a query")
+	}
+
+	query := &Query{
+		ID:       binary.BigEndian.Uint16(msg[0:2]),
+		Opcode:   uint8(flags>>11) & 0x0F,
+		Recursed: flags&0x0100 != 0,
+	}
+
+	if binary.BigEndian.Uint16(msg[4:6]) != 1 {
+		return query, errors.New("query must contain exactly one question")
+	}
+
+	name, offset, err := readName(msg, headerSize)
+	if err != nil {
+		return query, err
+	}
+	if offset+4 > len(msg) {
+		return query, errMalformed
+	}
+
+	query.Question = Question{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[offset : offset+2]),
+		Class: binary.BigEndian.Uint16(msg[offset+2 : offset+4]),
+	}
+	query.questionBytes = msg[headerSize : offset+4]
+
+	return query, nil
+}
+
+// readName decodes a possibly compressed domain name starting at offset and
+// returns it in lower case without the trailing dot, along with the offset
+// right after the name.
+func readName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+
+	// Bound the number of compression pointers followed to avoid loops
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errMalformed
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, nil
+
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3FFF)
+			jumps++
+
+		case length&0xC0 != 0:
+			return "", 0, errMalformed
+
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// BuildResponse encodes an authoritative response to the query with the given
+// response code and answers.
+func BuildResponse(query *Query, rcode int, answers []Answer) []byte {
+	msg := make([]byte, headerSize, 512)
+
+	// QR, opcode, AA and RD echoed from the query; no recursion available
+	flags := uint16(0x8000) | uint16(query.Opcode&0x0F)<<11 | uint16(rcode&0x0F)
+	if rcode == RcodeSuccess {
+		flags |= 0x0400
+	}
+	if query.Recursed {
+		flags |= 0x0100
+	}
+
+	binary.BigEndian.PutUint16(msg[0:2], query.ID)
+	binary.BigEndian.PutUint16(msg[2:4], flags)
+
+	if query.questionBytes == nil {
+		return msg
+	}
+
+	binary.BigEndian.PutUint16(msg[4:6], 1)
+	binary.BigEndian.PutUint16(msg[6:8], uint16(len(answers)))
+	msg = append(msg, query.questionBytes...)
+
+	for _, answer := range answers {
+		// The owner name points back to the question name
+		msg = binary.BigEndian.AppendUint16(msg, 0xC000|headerSize)
+		msg = binary.BigEndian.AppendUint16(msg, answer.Type)
+		msg = binary.BigEndian.AppendUint16(msg, ClassIN)
+		msg = binary.BigEndian.AppendUint32(msg, answer.TTL)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(answer.Data)))
+		msg = append(msg, answer.Data...)
+	}
+
+	return msg
+}
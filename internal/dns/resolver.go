@@ -0,0 +1,191 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// ResolverMarker is the first line of every resolver file written by the tool.
+// Files without it were not created by us and are never modified or removed.
+const ResolverMarker = "# Managed by localhost. Do not edit."
+
+// Resolver backends, i.e. the ways an OS can be told to send a TLD to our responder.
+const (
+	ResolverMacOS   = "macos"   // /etc/resolver/<tld>
+	ResolverSystemd = "systemd" // /etc/systemd/resolved.conf.d/localhost-<tld>.conf
+)
+
+const (
+	macOSResolverDir   = "etc/resolver"
+	systemdResolverDir = "etc/systemd/resolved.conf.d"
+	systemdFilePrefix  = "localhost-"
+)
+
+// DefaultResolverBackend returns the backend matching the running OS.
+func DefaultResolverBackend() string {
+	if runtime.GOOS == "darwin" {
+		return ResolverMacOS
+	}
+	return ResolverSystemd
+}
+
+// ResolverConfig describes where resolver files are written and which responder they point to.
+type ResolverConfig struct {
+	Root    string // Root directory the system paths are relative to, "/" on a live system
+	Backend string
+	Host    string
+	Port    int
+}
+
+// ResolverFile is a resolver file owned by the tool.
+type ResolverFile struct {
+	TLD     string
+	Backend string
+	Path    string
+}
+
+// Path returns the location of the resolver file for the TLD.
+func (c ResolverConfig) Path(tld string) (string, error) {
+	switch c.Backend {
+	case ResolverMacOS:
+		return filepath.Join(c.Root, macOSResolverDir, tld), nil
+	case ResolverSystemd:
+		return filepath.Join(c.Root, systemdResolverDir, systemdFilePrefix+tld+".conf"), nil
+	default:
+		return "", fmt.Errorf("unknown resolver backend '%s' (expected %s or %s)", c.Backend, ResolverMacOS, ResolverSystemd)
+	}
+}
+
+// Content returns the resolver file sending queries for the TLD to the responder.
+func (c ResolverConfig) Content(tld string) string {
+	if c.Backend == ResolverSystemd {
+		return fmt.Sprintf("%s\n[Resolve]\nDNS=%s\nDomains=~%s\n", ResolverMarker, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), tld)
+	}
+	return fmt.Sprintf("%s\nnameserver %s\nport %d\n", ResolverMarker, c.Host, c.Port)
+}
+
+// InstallResolver writes the resolver file for the TLD and returns its path.
+// An existing file is only replaced when it is owned by the tool.
+func InstallResolver(cfg ResolverConfig, tld string) (string, error) {
+	tld = normalizeName(tld)
+	if tld == "" || strings.ContainsAny(tld, "*/ ") {
+		return "", fmt.Errorf("invalid TLD '%s'", tld)
+	}
+
+	path, err := cfg.Path(tld)
+	if err != nil {
+		return "", err
+	}
+
+	owned, err := isOwnedResolver(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err == nil && !owned {
+		return "", fmt.Errorf("%s already exists and was not created by localhost", path)
+	}
+
+	if utils.IsDryRun() {
+		fmt.Printf("DRY RUN: Would write resolver file %s.\n", path)
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %s", filepath.Dir(path), err.Error())
+	}
+
+	if err := utils.WriteFileAtomic(path, []byte(cfg.Content(tld)), 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// OwnedResolvers returns the resolver files created by the tool under the root,
+// across every backend.
+func OwnedResolvers(root string) ([]ResolverFile, error) {
+	var owned []ResolverFile
+
+	dirs := map[string]string{
+		ResolverMacOS:   filepath.Join(root, macOSResolverDir),
+		ResolverSystemd: filepath.Join(root, systemdResolverDir),
+	}
+
+	for _, backend := range []string{ResolverMacOS, ResolverSystemd} {
+		files, err := os.ReadDir(dirs[backend])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", dirs[backend], err.Error())
+		}
+
+		for _, file := range files {
+			if !file.Type().IsRegular() {
+				continue
+			}
+
+			tld := file.Name()
+			if backend == ResolverSystemd {
+				if !strings.HasPrefix(tld, systemdFilePrefix) || !strings.HasSuffix(tld, ".conf") {
+					continue
+				}
+				tld = strings.TrimSuffix(strings.TrimPrefix(tld, systemdFilePrefix), ".conf")
+			}
+
+			path := filepath.Join(dirs[backend], file.Name())
+			if ok, err := isOwnedResolver(path); err != nil || !ok {
+				continue
+			}
+
+			owned = append(owned, ResolverFile{TLD: tld, Backend: backend, Path: path})
+		}
+	}
+
+	return owned, nil
+}
+
+// UninstallResolver removes a resolver file owned by the tool, keeping a backup of it.
+// A file the tool did not create is left alone.
+func UninstallResolver(file ResolverFile) error {
+	if owned, err := isOwnedResolver(file.Path); err != nil || !owned {
+		return fmt.Errorf("%s was not created by localhost, leaving it untouched", file.Path)
+	}
+
+	if utils.IsDryRun() {
+		fmt.Printf("DRY RUN: Would remove resolver file %s.\n", file.Path)
+		return nil
+	}
+
+	if _, err := utils.CreateBackup(file.Path); err != nil {
+		return err
+	}
+
+	if err := os.Remove(file.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %s", file.Path, err.Error())
+	}
+	return nil
+}
+
+// isOwnedResolver reports whether the file starts with the ownership marker.
+func isOwnedResolver(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()) == ResolverMarker, nil
+}
@@ -0,0 +1,131 @@
+package dns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func TestInstallResolver(t *testing.T) {
+	root := t.TempDir()
+	utils.BackupDir = t.TempDir()
+
+	for _, tc := range []struct {
+		backend string
+		path    string
+		content string
+	}{
+		{ResolverMacOS, "etc/resolver/test", ResolverMarker + "\nnameserver 127.0.0.1\nport 5353\n"},
+		{ResolverSystemd, "etc/systemd/resolved.conf.d/localhost-test.conf", ResolverMarker + "\n[Resolve]\nDNS=127.0.0.1:5353\nDomains=~test\n"},
+	} {
+		cfg := ResolverConfig{Root: root, Backend: tc.backend, Host: "127.0.0.1", Port: 5353}
+
+		path, err := InstallResolver(cfg, "Test")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.backend, err)
+		}
+		if path != filepath.Join(root, tc.path) {
+			t.Errorf("%s: path = %s", tc.backend, path)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tc.content {
+			t.Errorf("%s: content = %q, want %q", tc.backend, data, tc.content)
+		}
+
+		// Installing again replaces our own file
+		cfg.Port = 5454
+		if _, err := InstallResolver(cfg, "test"); err != nil {
+			t.Errorf("%s: reinstalling: %v", tc.backend, err)
+		}
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), "5454") {
+			t.Errorf("%s: the file was not updated: %q", tc.backend, data)
+		}
+	}
+
+	if _, err := InstallResolver(ResolverConfig{Root: root, Backend: ResolverMacOS}, "*.test"); err == nil {
+		t.Error("invalid TLD: expected an error")
+	}
+}
+
+func TestInstallResolverRefusesForeignFile(t *testing.T) {
+	root := t.TempDir()
+	utils.BackupDir = t.TempDir()
+
+	// Written by hand or by another tool
+	path := filepath.Join(root, "etc/resolver/test")
+	foreign := "nameserver 10.0.0.1\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(foreign), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ResolverConfig{Root: root, Backend: ResolverMacOS, Host: "127.0.0.1", Port: 5353}
+	if _, err := InstallResolver(cfg, "test"); err == nil {
+		t.Error("InstallResolver overwrote a file without the marker")
+	}
+	if data, _ := os.ReadFile(path); string(data) != foreign {
+		t.Errorf("the foreign file was changed: %q", data)
+	}
+}
+
+func TestUninstallOnlyOwnedResolvers(t *testing.T) {
+	root := t.TempDir()
+	utils.BackupDir = t.TempDir()
+
+	for _, backend := range []string{ResolverMacOS, ResolverSystemd} {
+		cfg := ResolverConfig{Root: root, Backend: backend, Host: "127.0.0.1", Port: 5353}
+		if _, err := InstallResolver(cfg, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files the tool did not create, next to its own
+	foreign := map[string]string{
+		"etc/resolver/corp": "nameserver 10.0.0.1\n",
+		"etc/systemd/resolved.conf.d/localhost-corp.conf":  "[Resolve]\nDNS=10.0.0.1\n",
+		"etc/systemd/resolved.conf.d/other.conf":           ResolverMarker + "\n",
+		"etc/systemd/resolved.conf.d/localhost-vpn.conf.d": ResolverMarker + "\n",
+	}
+	for name, content := range foreign {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	owned, err := OwnedResolvers(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owned) != 2 || owned[0].TLD != "test" || owned[0].Backend != ResolverMacOS || owned[1].TLD != "test" || owned[1].Backend != ResolverSystemd {
+		t.Fatalf("OwnedResolvers = %+v", owned)
+	}
+
+	for _, file := range owned {
+		if err := UninstallResolver(file); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", file.Path)
+		}
+		if backups, _ := utils.ListBackups(file.Path); len(backups) != 1 {
+			t.Errorf("%s: %d backups, want 1", file.Path, len(backups))
+		}
+	}
+
+	for name, content := range foreign {
+		if data, err := os.ReadFile(filepath.Join(root, name)); err != nil || string(data) != content {
+			t.Errorf("%s was touched: %q, %v", name, data, err)
+		}
+	}
+
+	// Even when asked directly
+	corp := ResolverFile{TLD: "corp", Backend: ResolverMacOS, Path: filepath.Join(root, "etc/resolver/corp")}
+	if err := UninstallResolver(corp); err == nil {
+		t.Error("UninstallResolver removed a file without the marker")
+	}
+}
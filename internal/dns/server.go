@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the port the responder listens on when none is configured. It
+// is unprivileged and does not collide with mDNS (5353) or a system resolver (53).
+const DefaultPort = 5300
+
+// DefaultTTL is the TTL of the answers, kept short so changes show up quickly.
+const DefaultTTL = 60
+
+// tcpTimeout bounds how long an idle TCP client may keep a connection open.
+const tcpTimeout = 10 * time.Second
+
+// Server is a small authoritative DNS responder answering A and AAAA queries for
+// the names accepted by its Matcher and refusing everything else.
+type Server struct {
+	Addr    string
+	Matcher *Matcher
+	IPv4    netip.Addr
+	IPv6    netip.Addr
+	TTL     uint32
+}
+
+// NewServer returns a server listening on the given host and port that answers
+// with the loopback addresses.
+func NewServer(host string, port int, matcher *Matcher) *Server {
+	return &Server{
+		Addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		Matcher: matcher,
+		IPv4:    netip.MustParseAddr("127.0.0.1"),
+		IPv6:    netip.MustParseAddr("::1"),
+		TTL:     DefaultTTL,
+	}
+}
+
+// Handle answers a single wire-format query. It returns nil when the message is
+// too broken to be answered at all.
+func (s *Server) Handle(msg []byte) []byte {
+	query, err := ParseQuery(msg)
+	if query == nil {
+		return nil
+	}
+	if err != nil {
+		return BuildResponse(query, RcodeFormatError, nil)
+	}
+
+	if query.Opcode != 0 {
+		return BuildResponse(query, RcodeNotImplemented, nil)
+	}
+
+	question := query.Question
+	if question.Class != ClassIN || !s.Matcher.Match(question.Name) {
+		return BuildResponse(query, RcodeRefused, nil)
+	}
+
+	var answers []Answer
+	if (question.Type == TypeA || question.Type == TypeANY) && s.IPv4.IsValid() {
+		ip := s.IPv4.As4()
+		answers = append(answers, Answer{Type: TypeA, TTL: s.TTL, Data: ip[:]})
+	}
+	if (question.Type == TypeAAAA || question.Type == TypeANY) && s.IPv6.IsValid() {
+		ip := s.IPv6.As16()
+		answers = append(answers, Answer{Type: TypeAAAA, TTL: s.TTL, Data: ip[:]})
+	}
+
+	// Other record types for a name we own get an empty, successful answer
+	return BuildResponse(query, RcodeSuccess, answers)
+}
+
+// ListenAndServe binds the UDP and TCP sockets on s.Addr and serves until the
+// context is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	packetConn, err := net.ListenPacket("udp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %s", s.Addr, err.Error())
+	}
+
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %s", s.Addr, err.Error())
+	}
+
+	return s.Serve(ctx, packetConn, listener)
+}
+
+// Serve answers queries on already bound sockets until the context is
+// cancelled, then closes them. Either socket may be nil.
+func (s *Server) Serve(ctx context.Context, packetConn net.PacketConn, listener net.Listener) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2)
+
+	if packetConn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- s.serveUDP(packetConn)
+		}()
+	}
+
+	if listener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- s.serveTCP(listener)
+		}()
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errCh:
+	}
+
+	if packetConn != nil {
+		packetConn.Close()
+	}
+	if listener != nil {
+		listener.Close()
+	}
+	wg.Wait()
+
+	return serveErr
+}
+
+// serveUDP answers datagrams until the connection is closed.
+func (s *Server) serveUDP(conn net.PacketConn) error {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to read udp query: %s", err.Error())
+		}
+
+		if response := s.Handle(buf[:n]); response != nil {
+			conn.WriteTo(response, addr)
+		}
+	}
+}
+
+// serveTCP accepts connections until the listener is closed.
+func (s *Server) serveTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept tcp connection: %s", err.Error())
+		}
+
+		go s.handleTCP(conn)
+	}
+}
+
+// handleTCP answers the length-prefixed queries sent over a TCP connection.
+func (s *Server) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(tcpTimeout))
+
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		response := s.Handle(msg)
+		if response == nil {
+			return
+		}
+
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(response)))
+		if _, err := conn.Write(append(framed, response...)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer runs the responder on ephemeral UDP and TCP sockets for the
+// duration of the test and returns their addresses.
+func startServer(t *testing.T, tlds, patterns []string) (udpAddr, tcpAddr string) {
+	t.Helper()
+
+	matcher, err := NewMatcher(tlds, patterns)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	server := NewServer("127.0.0.1", 0, matcher)
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		packetConn.Close()
+		t.Fatalf("listen tcp: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, packetConn, listener) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	return packetConn.LocalAddr().String(), listener.Addr().String()
+}
+
+// resolver returns a pure Go resolver sending every query to the address over
+// the given network.
+func resolver(network, address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+func TestServerAnswersMatchingNames(t *testing.T) {
+	udpAddr, tcpAddr := startServer(t, []string{"test"}, []string{"*.shop.local"})
+
+	for _, transport := range []struct{ network, address string }{{"udp", udpAddr}, {"tcp", tcpAddr}} {
+		r := resolver(transport.network, transport.address)
+
+		for _, name := range []string{"test.", "app.test.", "deep.app.test.", "a.shop.local.", "b.a.shop.local."} {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+			v4, err := r.LookupNetIP(ctx, "ip4", name)
+			if err != nil {
+				cancel()
+				t.Fatalf("%s A %s: %v", transport.network, name, err)
+			}
+			if len(v4) != 1 || v4[0] != netip.MustParseAddr("127.0.0.1") {
+				t.Errorf("%s A %s = %v, want [127.0.0.1]", transport.network, name, v4)
+			}
+
+			v6, err := r.LookupNetIP(ctx, "ip6", name)
+			cancel()
+			if err != nil {
+				t.Fatalf("%s AAAA %s: %v", transport.network, name, err)
+			}
+			if len(v6) != 1 || v6[0] != netip.MustParseAddr("::1") {
+				t.Errorf("%s AAAA %s = %v, want [::1]", transport.network, name, v6)
+			}
+		}
+	}
+}
+
+func TestServerRefusesOtherNames(t *testing.T) {
+	udpAddr, tcpAddr := startServer(t, []string{"test"}, []string{"*.shop.local"})
+
+	// *.shop.local only covers the names below shop.local
+	for _, name := range []string{"example.com", "testing", "shop.local", "shop.local.evil"} {
+		// The Go resolver hides the response code, so the query is sent by hand
+		response := exchangeUDP(t, udpAddr, buildQuery(0x1234, name, TypeA))
+		if rcode := int(response[3] & 0x0F); rcode != RcodeRefused {
+			t.Errorf("udp %s: rcode %d, want REFUSED (%d)", name, rcode, RcodeRefused)
+		}
+		if id := binary.BigEndian.Uint16(response[0:2]); id != 0x1234 {
+			t.Errorf("udp %s: id %#x, want 0x1234", name, id)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_, err := resolver("tcp", tcpAddr).LookupNetIP(ctx, "ip4", name+".")
+		cancel()
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			t.Errorf("tcp %s: expected a DNS error, got %v", name, err)
+		}
+	}
+}
+
+func TestHandleMalformedQueries(t *testing.T) {
+	matcher, err := NewMatcher([]string{"test"}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	server := NewServer("127.0.0.1", 0, matcher)
+
+	valid := buildQuery(0xBEEF, "app.test", TypeA)
+
+	for _, tc := range []struct {
+		name string
+		msg  []byte
+	}{
+		{"truncated question", valid[:len(valid)-3]},
+		{"truncated label", valid[:headerSize+3]},
+		{"reserved label type", append(append([]byte{}, valid[:headerSize]...), 0x80, 0x00, 0x00, 0x01, 0x00, 0x01)},
+		{"pointer loop", append(append([]byte{}, valid[:headerSize]...), 0xC0, headerSize, 0x00, 0x01, 0x00, 0x01)},
+		{"two questions", func() []byte {
+			msg := append([]byte{}, valid...)
+			binary.BigEndian.PutUint16(msg[4:6], 2)
+			return msg
+		}()},
+	} {
+		response := server.Handle(tc.msg)
+		if len(response) < headerSize {
+			t.Errorf("%s: no response", tc.name)
+			continue
+		}
+		if rcode := int(response[3] & 0x0F); rcode != RcodeFormatError {
+			t.Errorf("%s: rcode %d, want FORMERR (%d)", tc.name, rcode, RcodeFormatError)
+		}
+		if id := binary.BigEndian.Uint16(response[0:2]); id != 0xBEEF {
+			t.Errorf("%s: id %#x, want 0xbeef", tc.name, id)
+		}
+	}
+
+	// A message shorter than a header cannot even be answered
+	if response := server.Handle(valid[:5]); response != nil {
+		t.Errorf("short message: got a response, want none")
+	}
+}
+
+// buildQuery encodes a recursive query for the name and type.
+func buildQuery(id uint16, name string, qtype uint16) []byte {
+	msg := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[2:4], 0x0100)
+	binary.BigEndian.PutUint16(msg[4:6], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, ClassIN)
+}
+
+// exchangeUDP sends the query to the address and returns the response.
+func exchangeUDP(t *testing.T, address string, query []byte) []byte {
+	t.Helper()
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write(query); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n < headerSize {
+		t.Fatalf("short response: %d bytes", n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,354 @@
+package doctor
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/config"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/system"
+)
+
+// Status is the outcome of a single check.
+type Status int
+
+const (
+	Pass Status = iota
+	Warn
+	Fail
+)
+
+// String returns the label printed in the report.
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "PASS"
+	case Warn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is the outcome of a check, with a remediation hint when it did not pass.
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	Hint   string
+}
+
+// Run performs every check and returns their results. It never changes anything
+// on the system: no installs, no restarts and no file writes.
+func Run() []Result {
+	results := []Result{
+		checkApacheInstalled(),
+		checkApacheRunning(),
+		checkVhostsEnabled(),
+		checkModule("ssl_module", "SSL"),
+		checkPHP(),
+		checkCertificate(),
+		checkSiteCertificates(),
+		checkPort(80),
+		checkPort(443),
+	}
+
+	results = append(results, checkSites()...)
+	results = append(results, checkMySQL())
+
+	return results
+}
+
+// HasFailures reports whether any of the results failed.
+func HasFailures(results []Result) bool {
+	for _, result := range results {
+		if result.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkApacheInstalled() Result {
+	result := Result{Name: "Apache installed"}
+	if paths.Get().Prefix != "" {
+		result.Name = "Homebrew httpd installed"
+	}
+
+	if err := system.CheckApacheInstalled(); err != nil {
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = fmt.Sprintf("Run '%s'.", system.InstallCommand(system.ApacheService))
+	}
+	return result
+}
+
+// startHint tells how to start the service with the service manager of this
+// machine, e.g. "Start Apache with 'systemctl start apache2'".
+func startHint(name string, s system.Service) string {
+	if command := system.StartCommand(s); command != "" {
+		return fmt.Sprintf("Start %s with '%s'", name, command)
+	}
+	return fmt.Sprintf("Start %s", name)
+}
+
+func checkApacheRunning() Result {
+	result := Result{Name: "Apache running"}
+
+	status, err := system.CheckApacheStatus()
+	switch {
+	case err != nil:
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = fmt.Sprintf("Make sure Apache is installed and %s exists.", paths.Get().HttpdConf)
+	case !status.Running():
+		result.Status = Fail
+		result.Detail = fmt.Sprintf("no live process in %s", status.PidFile)
+		result.Hint = startHint("Apache", system.ApacheService) + " or run 'localhost create'."
+	case len(status.Unbound()) > 0:
+		result.Status = Warn
+		result.Detail = fmt.Sprintf("pid %d, but port(s) %s do not accept connections", status.PID, joinPorts(status.Unbound()))
+		result.Hint = "Check the Apache error log; another program may hold the port."
+	default:
+		result.Detail = fmt.Sprintf("pid %d, up %s, listening on %s", status.PID, status.Uptime(), joinPorts(status.Bound))
+	}
+	return result
+}
+
+func checkVhostsEnabled() Result {
+	result := Result{Name: "httpd.conf includes the vhosts directory"}
+
+	enabled, err := config.CheckVhostsEnabled()
+	switch {
+	case err != nil:
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = fmt.Sprintf("Make sure Apache is installed and %s exists.", paths.Get().HttpdConf)
+	case !enabled:
+		result.Status = Fail
+		result.Detail = fmt.Sprintf("'Include %s' is missing", filepath.Join(paths.Get().VhostsDir, "*.conf"))
+		result.Hint = fmt.Sprintf("Run 'localhost create' once, or add the Include line to %s manually.", filepath.Base(paths.Get().HttpdConf))
+	}
+	return result
+}
+
+func checkModule(module, label string) Result {
+	result := Result{Name: fmt.Sprintf("%s module loaded", label)}
+	if err := system.CheckModuleLoaded(module); err != nil {
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = fmt.Sprintf("Uncomment or add the 'LoadModule %s' line in %s.", module, paths.Get().HttpdConf)
+	}
+	return result
+}
+
+// checkPHP looks for the PHP module, which is only a warning: sites can run PHP
+// through PHP-FPM instead, which needs the FastCGI proxy module.
+func checkPHP() Result {
+	result := Result{Name: "PHP module loaded"}
+	for _, module := range []string{"php_module", "php7_module", "php5_module"} {
+		if system.CheckModuleLoaded(module) == nil {
+			return result
+		}
+	}
+
+	if system.CheckModuleLoaded("proxy_fcgi_module") == nil {
+		result.Detail = "not loaded, PHP runs through PHP-FPM (proxy_fcgi_module)"
+		return result
+	}
+
+	result.Status = Warn
+	result.Detail = fmt.Sprintf("neither the PHP module nor proxy_fcgi_module is loaded in %s", paths.Get().HttpdConf)
+	result.Hint = "Run 'localhost create' for a PHP site, or pass -php=<version> to serve it through PHP-FPM."
+	return result
+}
+
+func checkCertificate() Result {
+	result := Result{Name: "SSL certificate present and valid"}
+
+	cert, err := system.CheckSSLCertificate()
+	switch {
+	case err != nil && cert != nil:
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = "Run 'localhost certs renew --all' to issue a new certificate."
+	case err != nil:
+		result.Status = Fail
+		result.Detail = err.Error()
+		result.Hint = "Run 'localhost create' to generate a certificate."
+	case time.Until(cert.NotAfter) < system.CertificateWarnWindow():
+		result.Status = Warn
+		result.Detail = fmt.Sprintf("expires on %s", cert.NotAfter.Format("2006-01-02"))
+		result.Hint = "Run 'localhost certs renew --all' before it expires."
+	default:
+		result.Detail = fmt.Sprintf("valid until %s", cert.NotAfter.Format("2006-01-02"))
+	}
+	return result
+}
+
+// checkSiteCertificates reports the site certificates that cannot be served or
+// expire within the cert_warn_days window.
+func checkSiteCertificates() Result {
+	result := Result{Name: "Site certificates valid"}
+
+	window := system.CertificateWarnWindow()
+	list, err := system.Certificates()
+	if err != nil {
+		result.Status = Warn
+		result.Detail = err.Error()
+		return result
+	}
+
+	sites := 0
+	var invalid, expiring []string
+	for _, cert := range list {
+		if cert.Domain == "" {
+			continue
+		}
+		sites++
+
+		if problem := cert.Problem(); problem != "" {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", cert.Domain, problem))
+		} else if cert.ExpiresWithin(window) {
+			expiring = append(expiring, fmt.Sprintf("%s (%d day(s) left)", cert.Domain, cert.DaysLeft()))
+		}
+	}
+
+	switch {
+	case len(invalid) > 0:
+		result.Status = Fail
+		result.Detail = strings.Join(invalid, ", ")
+		result.Hint = "Run 'localhost certs renew --all', or 'localhost create' again for a certificate that cannot be read."
+	case len(expiring) > 0:
+		result.Status = Warn
+		result.Detail = fmt.Sprintf("expiring soon: %s", strings.Join(expiring, ", "))
+		result.Hint = "Run 'localhost certs renew --all' before they expire."
+	default:
+		result.Detail = fmt.Sprintf("%d certificate(s)", sites)
+	}
+	return result
+}
+
+func checkPort(port int) Result {
+	apache := paths.Get().ApacheName
+	result := Result{Name: fmt.Sprintf("Port %d owned by %s", port, apache)}
+
+	owner, err := system.PortOwner(port)
+	switch {
+	case err != nil:
+		result.Status = Warn
+		result.Detail = err.Error()
+		result.Hint = "Make sure 'lsof' is available to inspect listening ports."
+	case owner == "":
+		result.Status = Fail
+		result.Detail = "nothing is listening"
+		result.Hint = startHint("Apache", system.ApacheService) + fmt.Sprintf(" and check 'Listen %d' in %s.", port, filepath.Base(paths.Get().HttpdConf))
+	case owner != apache:
+		result.Status = Fail
+		result.Detail = fmt.Sprintf("owned by '%s'", owner)
+		result.Hint = fmt.Sprintf("Stop '%s' or move it to another port.", owner)
+	}
+	return result
+}
+
+// checkSites cross-checks the vhost files, the hosts file entries and the document roots.
+func checkSites() []Result {
+	vhosts, err := config.ListVhosts()
+	if err != nil {
+		return []Result{{Name: "Virtual hosts readable", Status: Fail, Detail: err.Error(), Hint: "Run 'localhost create' to set up the vhosts directory."}}
+	}
+
+	hosts, err := config.LoadHostsFile(paths.Get().HostsFile)
+	if err != nil {
+		return []Result{{Name: "Hosts file readable", Status: Fail, Detail: err.Error()}}
+	}
+
+	hostsResult := Result{Name: "Hosts entries match vhost files"}
+	rootsResult := Result{Name: "Document roots exist"}
+	var missingHosts, orphanHosts, missingRoots []string
+
+	served := map[string]bool{}
+	for _, vhost := range vhosts {
+		served[vhost.Domain] = true
+		if len(hosts.Addresses(vhost.Domain)) == 0 {
+			missingHosts = append(missingHosts, vhost.Domain)
+		}
+
+		for _, root := range vhost.DocumentRoots {
+			if info, err := os.Stat(root); err != nil || !info.IsDir() {
+				missingRoots = append(missingRoots, fmt.Sprintf("%s (%s)", root, vhost.Domain))
+			}
+		}
+	}
+
+	// Only loopback entries are expected to have a local vhost
+	for _, domain := range hosts.Domains() {
+		if served[domain] || !isLoopback(hosts.Addresses(domain)) {
+			continue
+		}
+		orphanHosts = append(orphanHosts, domain)
+	}
+
+	switch {
+	case len(missingHosts) > 0:
+		hostsResult.Status = Fail
+		hostsResult.Detail = fmt.Sprintf("no hosts entry for %s", strings.Join(missingHosts, ", "))
+		hostsResult.Hint = "Re-run 'localhost create' for these domains."
+	case len(orphanHosts) > 0:
+		hostsResult.Status = Warn
+		hostsResult.Detail = fmt.Sprintf("no vhost file for %s", strings.Join(orphanHosts, ", "))
+		hostsResult.Hint = "Run 'localhost delete' for domains you no longer use."
+	default:
+		hostsResult.Detail = fmt.Sprintf("%d site(s)", len(vhosts))
+	}
+
+	if len(missingRoots) > 0 {
+		rootsResult.Status = Fail
+		rootsResult.Detail = fmt.Sprintf("missing %s", strings.Join(missingRoots, ", "))
+		rootsResult.Hint = "Restore the project directories or delete the sites that use them."
+	}
+
+	return []Result{hostsResult, rootsResult}
+}
+
+func checkMySQL() Result {
+	result := Result{Name: "MySQL reachable"}
+	if err := system.CheckMySQLInstalled(); err != nil {
+		result.Status = Warn
+		result.Detail = err.Error()
+		result.Hint = fmt.Sprintf("Run '%s' if your projects need a database.", system.InstallCommand(system.MySQLService))
+		return result
+	}
+
+	if err := system.CheckMySQLReachable(); err != nil {
+		result.Status = Warn
+		result.Detail = err.Error()
+		result.Hint = startHint("MySQL", system.MySQLService) + "."
+	}
+	return result
+}
+
+// joinPorts renders a list of ports, e.g. "80, 443".
+func joinPorts(ports []int) string {
+	var list []string
+	for _, port := range ports {
+		list = append(list, strconv.Itoa(port))
+	}
+	return strings.Join(list, ", ")
+}
+
+// isLoopback reports whether all the addresses are loopback addresses.
+func isLoopback(addresses []string) bool {
+	for _, address := range addresses {
+		addr, err := netip.ParseAddr(address)
+		if err != nil || !addr.IsLoopback() {
+			return false
+		}
+	}
+	return len(addresses) > 0
+}
@@ -0,0 +1,313 @@
+package paths
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// Paths is the file system layout the tool works with: where Apache keeps its
+// configuration, where the virtual hosts and certificates go and where the tool
+// keeps its own state. It is resolved once at startup and every package reads
+// its locations from it.
+type Paths struct {
+	Root         string // Directory every system path is relative to, "/" on a live system
+	Prefix       string // Homebrew prefix, empty for distribution packages
+	HttpdConf    string
+	HttpdSSLConf string
+	VhostsDir    string
+	SSLDir       string
+	PHPModule    string
+	ModulesDir   string // Directory of the Apache modules as LoadModule sees it: absolute or relative to ServerRoot
+	ApacheName   string // Name of the Apache processes, e.g. "apache2" on Debian
+	ApacheUser   string // User and group the Apache workers run as, empty for the user running the tool
+	ApacheGroup  string
+	HostsFile    string
+	BackupDir    string
+	LogDir       string // Logs of the sites without a document root, e.g. reverse proxies
+	TemplatesDir string // User vhost templates overriding the built-in ones, not relative to Root
+	ConfigFile   string // The config file the overrides were read from, if any
+}
+
+// SSLCertificateFile returns the path of the shared SSL certificate.
+func (p *Paths) SSLCertificateFile() string {
+	return filepath.Join(p.SSLDir, "server.crt")
+}
+
+// SSLCertificateKeyFile returns the path of the shared SSL certificate key.
+func (p *Paths) SSLCertificateKeyFile() string {
+	return filepath.Join(p.SSLDir, "server.key")
+}
+
+// CACertificateFile returns the path of the root certificate of the local certificate authority.
+func (p *Paths) CACertificateFile() string {
+	return filepath.Join(p.SSLDir, "ca", "rootCA.crt")
+}
+
+// CAKeyFile returns the path of the key of the local certificate authority.
+func (p *Paths) CAKeyFile() string {
+	return filepath.Join(p.SSLDir, "ca", "rootCA.key")
+}
+
+// SiteCertificatesDir returns the directory holding a subdirectory per site certificate.
+func (p *Paths) SiteCertificatesDir() string {
+	return filepath.Join(p.SSLDir, "sites")
+}
+
+// SiteCertificateFile returns the path of the certificate issued for the domain.
+func (p *Paths) SiteCertificateFile(domain string) string {
+	return filepath.Join(p.SiteCertificatesDir(), domain, "server.crt")
+}
+
+// SiteCertificateKeyFile returns the path of the key of the certificate issued for the domain.
+func (p *Paths) SiteCertificateKeyFile(domain string) string {
+	return filepath.Join(p.SiteCertificatesDir(), domain, "server.key")
+}
+
+// ModuleFile returns the path LoadModule takes for the module file, e.g. "mod_ssl.so".
+func (p *Paths) ModuleFile(file string) string {
+	return filepath.Join(p.ModulesDir, file)
+}
+
+// VhostFile returns the path of the virtual host file for the domain.
+func (p *Paths) VhostFile(domain string) string {
+	return filepath.Join(p.VhostsDir, domain+".conf")
+}
+
+// settings maps the config file keys to their environment variables and fields.
+// System paths are relative to Root; user paths are not.
+var settings = []struct {
+	key    string
+	env    string
+	system bool
+	field  func(p *Paths) *string
+}{
+	{"httpd_conf", "LOCALHOST_HTTPD_CONF", true, func(p *Paths) *string { return &p.HttpdConf }},
+	{"httpd_ssl_conf", "LOCALHOST_HTTPD_SSL_CONF", true, func(p *Paths) *string { return &p.HttpdSSLConf }},
+	{"vhosts_dir", "LOCALHOST_VHOSTS_DIR", true, func(p *Paths) *string { return &p.VhostsDir }},
+	{"ssl_dir", "LOCALHOST_SSL_DIR", true, func(p *Paths) *string { return &p.SSLDir }},
+	{"php_module", "LOCALHOST_PHP_MODULE", true, func(p *Paths) *string { return &p.PHPModule }},
+	{"modules_dir", "LOCALHOST_MODULES_DIR", false, func(p *Paths) *string { return &p.ModulesDir }},
+	{"apache_name", "LOCALHOST_APACHE_NAME", false, func(p *Paths) *string { return &p.ApacheName }},
+	{"apache_user", "LOCALHOST_APACHE_USER", false, func(p *Paths) *string { return &p.ApacheUser }},
+	{"apache_group", "LOCALHOST_APACHE_GROUP", false, func(p *Paths) *string { return &p.ApacheGroup }},
+	{"hosts_file", "LOCALHOST_HOSTS_FILE", true, func(p *Paths) *string { return &p.HostsFile }},
+	{"backup_dir", "LOCALHOST_BACKUP_DIR", true, func(p *Paths) *string { return &p.BackupDir }},
+	{"log_dir", "LOCALHOST_LOG_DIR", true, func(p *Paths) *string { return &p.LogDir }},
+	{"templates_dir", "LOCALHOST_TEMPLATES_DIR", false, func(p *Paths) *string { return &p.TemplatesDir }},
+}
+
+var current *Paths
+
+// Init resolves the layout and the settings and makes them available through
+// Get and GetSettings. The root flag, when not empty, takes precedence over
+// every other source.
+func Init(rootFlag string) error {
+	p, err := Resolve(rootFlag)
+	if err != nil {
+		return err
+	}
+
+	s, err := ResolveSettings()
+	if err != nil {
+		return err
+	}
+
+	current = p
+	currentSettings = s
+	return nil
+}
+
+// Get returns the layout resolved by Init.
+func Get() *Paths {
+	if current == nil {
+		panic("paths: Get called before Init")
+	}
+	return current
+}
+
+// Resolve builds the layout. Values are taken, from lowest to highest priority,
+// from the detected installation, the config file, the LOCALHOST_* environment
+// variables and the --root flag.
+func Resolve(rootFlag string) (*Paths, error) {
+	file, err := loadConfigFile(ConfigFilePath())
+	if err != nil {
+		return nil, err
+	}
+	lookup := lookupIn(file)
+
+	root := lookup("root", "LOCALHOST_ROOT")
+	if rootFlag != "" {
+		root = rootFlag
+	}
+	if root == "" {
+		root = "/"
+	}
+
+	p := detect(lookup("prefix", "LOCALHOST_PREFIX"), root)
+	p.Root = root
+	p.TemplatesDir = filepath.Join(filepath.Dir(ConfigFilePath()), "templates")
+	if file != nil {
+		p.ConfigFile = ConfigFilePath()
+	}
+
+	for _, setting := range settings {
+		if value := lookup(setting.key, setting.env); value != "" {
+			*setting.field(p) = value
+		}
+	}
+
+	// Every system path lives under the root
+	for _, setting := range settings {
+		if field := setting.field(p); setting.system && *field != "" {
+			*field = filepath.Join(root, *field)
+		}
+	}
+
+	return p, nil
+}
+
+// detect returns the default layout of the Apache installation found on this
+// machine: Homebrew (Apple Silicon or Intel), Debian/Ubuntu or Fedora/RHEL.
+func detect(prefix, root string) *Paths {
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(root, path))
+		return err == nil
+	}
+
+	if prefix == "" {
+		prefix = brewPrefix()
+	}
+	if prefix == "" {
+		for _, candidate := range []string{"/opt/homebrew", "/usr/local"} {
+			if exists(filepath.Join(candidate, "etc/httpd/httpd.conf")) {
+				prefix = candidate
+				break
+			}
+		}
+	}
+
+	switch {
+	case prefix == "" && exists("/etc/apache2/apache2.conf"):
+		return &Paths{
+			HttpdConf:   "/etc/apache2/apache2.conf",
+			VhostsDir:   "/etc/apache2/localhost-vhosts",
+			SSLDir:      "/etc/apache2/ssl",
+			ModulesDir:  "/usr/lib/apache2/modules",
+			ApacheName:  "apache2",
+			ApacheUser:  "www-data",
+			ApacheGroup: "www-data",
+			HostsFile:   "/etc/hosts",
+			BackupDir:   "/var/backups/localhost",
+			LogDir:      "/var/log/apache2",
+		}
+	case prefix == "" && exists("/etc/httpd/conf/httpd.conf"):
+		return &Paths{
+			HttpdConf:    "/etc/httpd/conf/httpd.conf",
+			HttpdSSLConf: "/etc/httpd/conf.d/ssl.conf",
+			VhostsDir:    "/etc/httpd/localhost-vhosts",
+			SSLDir:       "/etc/httpd/ssl",
+			ModulesDir:   "modules", // Linked to /usr/lib64/httpd/modules
+			ApacheName:   "httpd",
+			ApacheUser:   "apache",
+			ApacheGroup:  "apache",
+			HostsFile:    "/etc/hosts",
+			BackupDir:    "/var/backups/localhost",
+			LogDir:       "/var/log/httpd",
+		}
+	}
+
+	if prefix == "" {
+		prefix = "/opt/homebrew"
+	}
+
+	return &Paths{
+		Prefix:       prefix,
+		HttpdConf:    filepath.Join(prefix, "etc/httpd/httpd.conf"),
+		HttpdSSLConf: filepath.Join(prefix, "etc/httpd/extra/httpd-ssl.conf"),
+		VhostsDir:    filepath.Join(prefix, "etc/httpd/extra/vhosts"),
+		SSLDir:       filepath.Join(prefix, "etc/httpd/ssl"),
+		PHPModule:    filepath.Join(prefix, "opt/php/lib/httpd/modules/libphp.so"),
+		ModulesDir:   "lib/httpd/modules",
+		ApacheName:   "httpd",
+		HostsFile:    "/etc/hosts",
+		BackupDir:    filepath.Join(prefix, "var/localhost/backups"),
+		LogDir:       filepath.Join(prefix, "var/log/httpd"),
+	}
+}
+
+// brewPrefix asks Homebrew for its prefix, returning an empty string when it is not installed.
+func brewPrefix() string {
+	if _, err := exec.LookPath("brew"); err != nil {
+		return ""
+	}
+
+	cmd := exec.Command("brew", "--prefix")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	if err := utils.RunAsOriginalUser(cmd); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// ConfigFilePath returns the location of the user config file. LOCALHOST_CONFIG
+// overrides the default ~/.config/localhost/config of the user running the tool.
+func ConfigFilePath() string {
+	if path := os.Getenv("LOCALHOST_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(utils.GetOriginalHome(), ".config", "localhost", "config")
+}
+
+// lookupIn returns the value of a setting: its environment variable, or else
+// its key in the config file.
+func lookupIn(file map[string]string) func(key, env string) string {
+	return func(key, env string) string {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+		return file[key]
+	}
+}
+
+// loadConfigFile reads "key = value" lines, ignoring blank lines and # comments.
+// A missing file yields no settings.
+func loadConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file %s: %s", path, err.Error())
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected 'key = value'", path, lineNumber)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %s", path, err.Error())
+	}
+
+	return values, nil
+}
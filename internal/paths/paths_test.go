@@ -0,0 +1,108 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolate keeps the machine's Homebrew and the user's config out of a test.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("PATH", "")
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(dir, "missing"))
+	for _, setting := range settings {
+		t.Setenv(setting.env, "")
+	}
+	for _, env := range []string{"LOCALHOST_ROOT", "LOCALHOST_PREFIX", "LOCALHOST_SERVICE_MANAGER", "LOCALHOST_CERT_KEY_TYPE", "LOCALHOST_CERT_VALIDITY_DAYS", "LOCALHOST_CERT_ORGANIZATION", "LOCALHOST_CERT_WARN_DAYS"} {
+		t.Setenv(env, "")
+	}
+	return dir
+}
+
+func TestResolveDetectsDebian(t *testing.T) {
+	root := isolate(t)
+	if err := os.MkdirAll(filepath.Join(root, "etc/apache2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc/apache2/apache2.conf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Resolve(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HttpdConf != filepath.Join(root, "etc/apache2/apache2.conf") {
+		t.Errorf("HttpdConf = %s", p.HttpdConf)
+	}
+	if p.ApacheName != "apache2" || p.ApacheUser != "www-data" || p.Prefix != "" {
+		t.Errorf("ApacheName, ApacheUser, Prefix = %q, %q, %q", p.ApacheName, p.ApacheUser, p.Prefix)
+	}
+	// Not a file system path: it stays as LoadModule sees it
+	if p.ModulesDir != "/usr/lib/apache2/modules" {
+		t.Errorf("ModulesDir = %s", p.ModulesDir)
+	}
+}
+
+func TestResolveOverrides(t *testing.T) {
+	dir := isolate(t)
+	config := filepath.Join(dir, "config")
+	t.Setenv("LOCALHOST_CONFIG", config)
+	if err := os.WriteFile(config, []byte(
+		"# Overrides\n"+
+			"root = /srv/sandbox\n"+
+			"prefix = /usr/local\n"+
+			"vhosts_dir = /file/vhosts\n"+
+			"hosts_file = \"/file/hosts\"\n"+
+			"apache_user = daemon\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The environment wins over the config file
+	t.Setenv("LOCALHOST_VHOSTS_DIR", "/env/vhosts")
+	t.Setenv("LOCALHOST_TEMPLATES_DIR", "/env/templates")
+
+	p, err := Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][2]string{
+		"Root":         {p.Root, "/srv/sandbox"},
+		"Prefix":       {p.Prefix, "/usr/local"},
+		"HttpdConf":    {p.HttpdConf, "/srv/sandbox/usr/local/etc/httpd/httpd.conf"},
+		"VhostsDir":    {p.VhostsDir, "/srv/sandbox/env/vhosts"},
+		"HostsFile":    {p.HostsFile, "/srv/sandbox/file/hosts"},
+		"ApacheUser":   {p.ApacheUser, "daemon"},
+		"TemplatesDir": {p.TemplatesDir, "/env/templates"},
+		"ConfigFile":   {p.ConfigFile, config},
+	} {
+		if got[0] != got[1] {
+			t.Errorf("%s = %s, want %s", name, got[0], got[1])
+		}
+	}
+
+	// The --root flag wins over everything
+	t.Setenv("LOCALHOST_ROOT", "/env/root")
+	p, err = Resolve("/flag/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root != "/flag/root" || p.VhostsDir != "/flag/root/env/vhosts" {
+		t.Errorf("Root, VhostsDir = %s, %s", p.Root, p.VhostsDir)
+	}
+}
+
+func TestResolveRejectsMalformedConfig(t *testing.T) {
+	dir := isolate(t)
+	config := filepath.Join(dir, "config")
+	t.Setenv("LOCALHOST_CONFIG", config)
+	if err := os.WriteFile(config, []byte("vhosts_dir /etc/vhosts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Resolve(""); err == nil {
+		t.Error("expected an error for a line without '='")
+	}
+}
@@ -0,0 +1,91 @@
+package paths
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// Settings are the options of the tool that are not locations. They come from
+// the same config file and LOCALHOST_* variables as the paths, and are parsed
+// and validated once at startup so a bad value is reported before any command
+// runs.
+type Settings struct {
+	ServiceManager string // Forced service manager ("brew", "launchctl", "systemd" or "fake"), detected when empty
+
+	CertKeyType      certs.KeyType // Key type of the certificates
+	CertValidity     time.Duration // Validity of the site certificates
+	CertOrganization string        // Organization of the certificate subjects
+	CertWarnWindow   time.Duration // How long before expiry the certificates are reported
+}
+
+// defaultCertWarnDays is how many days before expiry certificates are reported
+// when the cert_warn_days setting is not set.
+const defaultCertWarnDays = 30
+
+var currentSettings *Settings
+
+// GetSettings returns the settings resolved by Init.
+func GetSettings() *Settings {
+	if currentSettings == nil {
+		panic("paths: GetSettings called before Init")
+	}
+	return currentSettings
+}
+
+// ResolveSettings reads the settings from the config file and the LOCALHOST_*
+// environment variables, falling back to the defaults.
+func ResolveSettings() (*Settings, error) {
+	file, err := loadConfigFile(ConfigFilePath())
+	if err != nil {
+		return nil, err
+	}
+	lookup := lookupIn(file)
+
+	s := &Settings{
+		CertKeyType:      certs.ECDSA,
+		CertValidity:     certs.DefaultValidity,
+		CertOrganization: certs.DefaultOrganization,
+		CertWarnWindow:   defaultCertWarnDays * 24 * time.Hour,
+	}
+
+	switch value := lookup("service_manager", "LOCALHOST_SERVICE_MANAGER"); value {
+	case "", "brew", "launchctl", "systemd", "fake":
+		s.ServiceManager = value
+	default:
+		return nil, fmt.Errorf("invalid service_manager setting '%s': expected brew, launchctl, systemd or fake", value)
+	}
+
+	if value := lookup("cert_key_type", "LOCALHOST_CERT_KEY_TYPE"); value != "" {
+		keyType, err := certs.ParseKeyType(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cert_key_type setting: %s", err.Error())
+		}
+		s.CertKeyType = keyType
+	}
+
+	if value := lookup("cert_validity_days", "LOCALHOST_CERT_VALIDITY_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid cert_validity_days setting '%s': expected a number of days", value)
+		}
+		s.CertValidity = time.Duration(days) * 24 * time.Hour
+	}
+
+	if value := lookup("cert_organization", "LOCALHOST_CERT_ORGANIZATION"); value != "" {
+		s.CertOrganization = value
+	}
+
+	if value := lookup("cert_warn_days", "LOCALHOST_CERT_WARN_DAYS"); value != "" {
+		window, err := utils.ParseDays(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cert_warn_days setting: %s", err.Error())
+		}
+		s.CertWarnWindow = window
+	}
+
+	return s, nil
+}
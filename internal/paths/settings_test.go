@@ -0,0 +1,54 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+)
+
+func TestResolveSettings(t *testing.T) {
+	dir := isolate(t)
+
+	s, err := ResolveSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.CertKeyType != certs.ECDSA || s.CertValidity != certs.DefaultValidity || s.CertOrganization != certs.DefaultOrganization || s.CertWarnWindow != 30*24*time.Hour {
+		t.Errorf("defaults = %+v", s)
+	}
+
+	config := filepath.Join(dir, "config")
+	t.Setenv("LOCALHOST_CONFIG", config)
+	if err := os.WriteFile(config, []byte("cert_key_type = RSA\ncert_validity_days = 90\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LOCALHOST_CERT_ORGANIZATION", "Acme dev")
+	t.Setenv("LOCALHOST_SERVICE_MANAGER", "systemd")
+	t.Setenv("LOCALHOST_CERT_WARN_DAYS", "14d")
+
+	s, err = ResolveSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.CertKeyType != certs.RSA || s.CertValidity != 90*24*time.Hour || s.CertOrganization != "Acme dev" || s.ServiceManager != "systemd" || s.CertWarnWindow != 14*24*time.Hour {
+		t.Errorf("settings = %+v", s)
+	}
+}
+
+func TestInitRejectsInvalidSettings(t *testing.T) {
+	for env, value := range map[string]string{
+		"LOCALHOST_SERVICE_MANAGER":    "upstart",
+		"LOCALHOST_CERT_KEY_TYPE":      "dsa",
+		"LOCALHOST_CERT_VALIDITY_DAYS": "two years",
+		"LOCALHOST_CERT_WARN_DAYS":     "a month",
+	} {
+		root := isolate(t)
+		t.Setenv(env, value)
+		if err := Init(root); err == nil {
+			t.Errorf("%s=%s: expected an error", env, value)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package presets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Detection is the preset chosen for a document root and the files that led to it.
+type Detection struct {
+	Preset   string
+	Evidence []string
+}
+
+// detector recognises a kind of project, returning the evidence when it matches.
+type detector struct {
+	preset string
+	match  func(dir string) ([]string, bool)
+}
+
+// detectors are tried in order; the first match wins. Laravel comes before
+// Symfony since Laravel projects depend on Symfony components too.
+var detectors = []detector{
+	{"wordpress", detectWordPress},
+	{"laravel", detectLaravel},
+	{"symfony", detectSymfony},
+	{"spa", detectSPA},
+	{"static", detectStatic},
+}
+
+// Detect inspects the document root and picks the preset for it, falling back
+// to DefaultPreset when nothing is recognised. A document root that does not
+// exist yet has nothing to detect: the detection is empty.
+func Detect(dir string) Detection {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return Detection{}
+	}
+
+	for _, d := range detectors {
+		if evidence, ok := d.match(dir); ok {
+			return Detection{Preset: d.preset, Evidence: evidence}
+		}
+	}
+
+	return Detection{Preset: DefaultPreset, Evidence: []string{"no framework files found"}}
+}
+
+func detectWordPress(dir string) ([]string, bool) {
+	for _, name := range []string{"wp-config.php", "wp-config-sample.php"} {
+		if fileExists(dir, name) {
+			return []string{fmt.Sprintf("found %s", name)}, true
+		}
+	}
+	return nil, false
+}
+
+func detectLaravel(dir string) ([]string, bool) {
+	if !fileExists(dir, "artisan") || !composerRequires(dir, "laravel/framework") {
+		return nil, false
+	}
+	return []string{"found artisan", "composer.json requires laravel/framework"}, true
+}
+
+func detectSymfony(dir string) ([]string, bool) {
+	if fileExists(dir, "symfony.lock") {
+		return []string{"found symfony.lock"}, true
+	}
+	if composerRequires(dir, "symfony/framework-bundle") {
+		return []string{"composer.json requires symfony/framework-bundle"}, true
+	}
+	return nil, false
+}
+
+func detectSPA(dir string) ([]string, bool) {
+	if hasPHP(dir) || !packageRequires(dir, "vite") {
+		return nil, false
+	}
+	return []string{"package.json depends on vite", "no PHP files or composer.json"}, true
+}
+
+func detectStatic(dir string) ([]string, bool) {
+	if hasPHP(dir) || !fileExists(dir, "index.html") {
+		return nil, false
+	}
+	return []string{"found index.html", "no PHP files or composer.json"}, true
+}
+
+func fileExists(dir, name string) bool {
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && !info.IsDir()
+}
+
+// hasPHP reports whether the project looks like a PHP project: it has a
+// composer.json or PHP files at its root or in public/.
+func hasPHP(dir string) bool {
+	if fileExists(dir, "composer.json") {
+		return true
+	}
+	for _, pattern := range []string{"*.php", "public/*.php"} {
+		if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// composerRequires reports whether composer.json requires the package.
+func composerRequires(dir, pkg string) bool {
+	var manifest struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if !readJSON(filepath.Join(dir, "composer.json"), &manifest) {
+		return false
+	}
+
+	_, inRequire := manifest.Require[pkg]
+	_, inRequireDev := manifest.RequireDev[pkg]
+	return inRequire || inRequireDev
+}
+
+// packageRequires reports whether package.json depends on the package.
+func packageRequires(dir, pkg string) bool {
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if !readJSON(filepath.Join(dir, "package.json"), &manifest) {
+		return false
+	}
+
+	_, inDependencies := manifest.Dependencies[pkg]
+	_, inDevDependencies := manifest.DevDependencies[pkg]
+	return inDependencies || inDevDependencies
+}
+
+// readJSON decodes a JSON file, reporting false when it is missing or invalid.
+func readJSON(path string, v interface{}) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
@@ -0,0 +1,81 @@
+package presets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tree creates a document root holding the files, keyed by their relative path.
+func tree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		files    map[string]string
+		preset   string
+		evidence string
+	}{
+		{"laravel", map[string]string{
+			"artisan":       "#!/usr/bin/env php\n",
+			"composer.json": `{"require": {"laravel/framework": "^11.0", "symfony/console": "^7.0"}}`,
+		}, "laravel", "found artisan, composer.json requires laravel/framework"},
+		{"artisan without laravel", map[string]string{
+			"artisan":       "",
+			"composer.json": `{"require": {"php": "^8.2"}}`,
+		}, DefaultPreset, "no framework files found"},
+		{"wordpress", map[string]string{
+			"wp-config.php": "<?php\n",
+			"index.php":     "<?php\n",
+		}, "wordpress", "found wp-config.php"},
+		{"symfony lock", map[string]string{
+			"symfony.lock":  "{}",
+			"composer.json": "{}",
+		}, "symfony", "found symfony.lock"},
+		{"symfony bundle", map[string]string{
+			"composer.json": `{"require": {"symfony/framework-bundle": "^7.0"}}`,
+		}, "symfony", "composer.json requires symfony/framework-bundle"},
+		{"spa", map[string]string{
+			"index.html":   "<!doctype html>",
+			"package.json": `{"devDependencies": {"vite": "^5.0"}}`,
+		}, "spa", "package.json depends on vite, no PHP files or composer.json"},
+		{"static", map[string]string{
+			"index.html": "<!doctype html>",
+		}, "static", "found index.html, no PHP files or composer.json"},
+		{"static with PHP", map[string]string{
+			"index.html":        "<!doctype html>",
+			"public/index.php":  "<?php\n",
+			"assets/styles.css": "",
+		}, DefaultPreset, "no framework files found"},
+		{"empty", nil, DefaultPreset, "no framework files found"},
+	} {
+		got := Detect(tree(t, tc.files))
+		if got.Preset != tc.preset {
+			t.Errorf("%s: preset = %q, want %q", tc.name, got.Preset, tc.preset)
+		}
+		if evidence := strings.Join(got.Evidence, ", "); evidence != tc.evidence {
+			t.Errorf("%s: evidence = %q, want %q", tc.name, evidence, tc.evidence)
+		}
+	}
+}
+
+func TestDetectMissingDocumentRoot(t *testing.T) {
+	got := Detect(filepath.Join(t.TempDir(), "public"))
+	if got.Preset != "" || len(got.Evidence) != 0 {
+		t.Errorf("Detect = %+v, want an empty detection", got)
+	}
+}
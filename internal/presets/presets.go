@@ -0,0 +1,135 @@
+package presets
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/config"
+)
+
+// DefaultPreset is the preset used when none is chosen: a PHP project serving
+// its public directory, as the tool has always set up.
+const DefaultPreset = "php"
+
+// Service is a local service a preset needs besides Apache.
+type Service string
+
+const (
+	MySQL Service = "mysql"
+	PHP   Service = "php"
+)
+
+// Preset describes how a kind of project is served.
+type Preset struct {
+	Name        string
+	Description string
+	PublicDir   string   // The web root, relative to the document root; empty for the document root itself
+	Directives  []string // Extra directives for the web root, e.g. the front controller fallback
+	Services    []Service
+	Scaffold    config.VhostScaffold // The placeholder page; {domain} in the content is replaced by the domain
+}
+
+// Needs reports whether the preset requires the service.
+func (p Preset) Needs(service Service) bool {
+	for _, s := range p.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply points the vhost data at the preset's web root and adds its directives
+// and scaffold file.
+func (p Preset) Apply(data *config.VhostData) {
+	data.PublicDir = filepath.Join(data.DocumentRoot, p.PublicDir)
+	data.Directives = append(data.Directives, p.Directives...)
+
+	data.Scaffold = p.Scaffold
+	data.Scaffold.Content = strings.ReplaceAll(p.Scaffold.Content, "{domain}", data.Domain)
+}
+
+var registry = map[string]Preset{}
+
+// Register adds a preset to the registry, replacing any preset with the same name.
+func Register(p Preset) {
+	registry[p.Name] = p
+}
+
+// Get returns the named preset.
+func Get(name string) (Preset, error) {
+	p, ok := registry[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names returns the names of the registered presets, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const (
+	phpScaffold  = "<?php\necho 'It worked! You are on {domain} domain.';\n"
+	htmlScaffold = "<!DOCTYPE html>\n<html>\n<head><title>{domain}</title></head>\n<body><p>It worked! You are on {domain} domain.</p></body>\n</html>\n"
+)
+
+func init() {
+	// Front controllers get every request that does not match a file on disk
+	frontController := []string{"DirectoryIndex index.php", "FallbackResource /index.php"}
+
+	// The site logs live in the project root, keep them out of sites served from it
+	hideLogs := "RedirectMatch 404 ^/_logs/"
+
+	Register(Preset{
+		Name:        "php",
+		Description: "Plain PHP project served from public/",
+		PublicDir:   "public",
+		Services:    []Service{MySQL, PHP},
+		Scaffold:    config.VhostScaffold{Name: "index.php", Content: phpScaffold},
+	})
+	Register(Preset{
+		Name:        "laravel",
+		Description: "Laravel application served from public/ through index.php",
+		PublicDir:   "public",
+		Directives:  frontController,
+		Services:    []Service{MySQL, PHP},
+		Scaffold:    config.VhostScaffold{Name: "index.php", Content: phpScaffold},
+	})
+	Register(Preset{
+		Name:        "symfony",
+		Description: "Symfony application served from public/ through index.php",
+		PublicDir:   "public",
+		Directives:  frontController,
+		Services:    []Service{MySQL, PHP},
+		Scaffold:    config.VhostScaffold{Name: "index.php", Content: phpScaffold},
+	})
+	Register(Preset{
+		Name:        "wordpress",
+		Description: "WordPress site served from the project root with pretty permalinks",
+		Directives:  append([]string{hideLogs}, frontController...),
+		Services:    []Service{MySQL, PHP},
+		Scaffold:    config.VhostScaffold{Name: "index.php", Content: phpScaffold},
+	})
+	Register(Preset{
+		Name:        "static",
+		Description: "Static HTML site served from the project root",
+		Directives:  []string{hideLogs, "DirectoryIndex index.html"},
+		Scaffold:    config.VhostScaffold{Name: "index.html", Content: htmlScaffold},
+	})
+	Register(Preset{
+		Name:        "spa",
+		Description: "Single-page application built into dist/, with an index.html fallback for client-side routes",
+		PublicDir:   "dist",
+		Directives:  []string{"DirectoryIndex index.html", "FallbackResource /index.html"},
+		Scaffold:    config.VhostScaffold{Name: "index.html", Content: htmlScaffold},
+	})
+}
@@ -7,11 +7,21 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
 // CheckApacheInstalled verifies if Apache is installed on the system via Homebrew.
 func CheckApacheInstalled() error {
+	// Distribution packages install apachectl (apache2ctl on Debian)
+	if paths.Get().Prefix == "" {
+		if _, err := apachectl(); err != nil {
+			return fmt.Errorf("Apache is not installed or not accessible. Install it with '%s'", InstallCommand(ApacheService))
+		}
+		return nil
+	}
+
 	cmd := exec.Command("brew", "list", "httpd")
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -19,32 +29,63 @@ func CheckApacheInstalled() error {
 
 	err := utils.RunAsOriginalUser(cmd)
 	if err != nil {
-		return errors.New("Apache is not installed or not accessible. Install it using Homebrew: 'brew install httpd'")
+		return fmt.Errorf("Apache is not installed or not accessible. Install it using Homebrew: '%s'", InstallCommand(ApacheService))
 	}
 
-	// Apache is installed
-	fmt.Println("✔ Apache is installed.")
 	return nil
 }
 
-// CheckApacheRunning verifies if Apache is currently running.
+// CheckApacheRunning verifies if Apache is currently running, see CheckApacheStatus.
 func CheckApacheRunning() error {
-	cmd := exec.Command("ps", "aux")
+	status, err := CheckApacheStatus()
+	if err != nil {
+		return fmt.Errorf("failed to check the Apache status: %s", err.Error())
+	}
+
+	if !status.Running() {
+		return errors.New("Apache is not running")
+	}
+
+	return nil
+}
+
+// CheckModuleLoaded verifies that httpd.conf, or a file it includes, loads the
+// given module (e.g. "ssl_module").
+func CheckModuleLoaded(module string) error {
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
+	if err != nil {
+		return err
+	}
+
+	if !conf.ModuleLoaded(module) {
+		return fmt.Errorf("%s is not loaded in httpd.conf", module)
+	}
+	return nil
+}
+
+// PortOwner returns the name of the process listening on the given TCP port, or
+// an empty string when nothing listens on it.
+func PortOwner(port int) (string, error) {
+	cmd := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fc")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
 
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("failed to check running processes: %s", err.Error())
+	// lsof exits with 1 when no process matches
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && out.Len() == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to check port %d: %s", port, err.Error())
 	}
 
-	if strings.Contains(out.String(), "httpd") {
-		fmt.Println("✔ Apache is running.")
-		return nil
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "c") {
+			return strings.TrimPrefix(line, "c"), nil
+		}
 	}
 
-	return errors.New("Apache is not running")
+	return "", nil
 }
 
 // InstallApache attempts to install Apache using Homebrew.
@@ -71,58 +112,120 @@ func InstallApache() error {
 	return nil
 }
 
-// RestartApache attempts to restart Apache and flushes the DNS cache.
-func RestartApache() error {
+// apacheAction is what Apache needs for the changes made by the current command.
+type apacheAction int
+
+const (
+	noAction      apacheAction = iota
+	reloadAction               // Configuration changes only: a graceful reload is enough
+	restartAction              // A module was added or Apache is not running
+)
+
+// pendingAction is the strongest action requested during the current command.
+var pendingAction = noAction
+
+// RequestApacheReload records that the configuration changed. The reload
+// happens once, in ApplyApacheChanges.
+func RequestApacheReload() {
+	if pendingAction < reloadAction {
+		pendingAction = reloadAction
+	}
+}
+
+// RequestApacheRestart records that Apache needs a full restart, e.g. to load a
+// new module. The restart happens once, in ApplyApacheChanges.
+func RequestApacheRestart() {
+	pendingAction = restartAction
+}
+
+// ApplyApacheChanges applies the reload or restart requested during the command,
+// if any, and flushes the DNS cache once. A failed flush is a *DNSFlushError.
+func ApplyApacheChanges() error {
+	action := pendingAction
+	pendingAction = noAction
+
+	var err error
+	switch action {
+	case noAction:
+		return nil
+	case reloadAction:
+		err = ReloadApache()
+	default:
+		err = RestartApache()
+	}
+	if err != nil {
+		return err
+	}
+
+	return FlushDNSCache()
+}
+
+// ReloadApache validates the configuration and gracefully reloads Apache through
+// the service manager, letting the requests in flight finish. It falls back to
+// a restart when Apache is not running. When the configuration is invalid, the changes of the current
+// command are reverted and Apache is left running as it was.
+func ReloadApache() error {
 	if utils.IsDryRun() {
-		fmt.Println("DRY RUN: Would restart Apache server and flush the DNS cache.")
+		fmt.Println("DRY RUN: Would gracefully reload Apache server.")
 		return nil
 	}
 
-	// Restart Apache
-	restartErr := utils.Spinner("Restarting Apache server...", func() error {
-		cmd := exec.Command("brew", "services", "restart", "httpd")
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		return utils.RunAsOriginalUser(cmd)
+	// Never reload into a broken configuration
+	if err := testConfigOrRevert(); err != nil {
+		return err
+	}
+
+	services, err := Services()
+	if err != nil {
+		return err
+	}
+
+	reloadErr := utils.Spinner("Reloading Apache server...", func() error {
+		return services.Reload(ApacheService)
 	})
 
-	if restartErr != nil {
-		return fmt.Errorf("failed to restart Apache: %s", restartErr.Error())
+	if reloadErr != nil {
+		utils.LogWarning(fmt.Sprintf("Graceful reload failed (%s), restarting Apache instead.", strings.TrimSpace(reloadErr.Error())))
+		return restartApache()
 	}
 
-	fmt.Println("✔ Apache restarted successfully.")
+	fmt.Println("✔ Apache reloaded successfully.")
+	return nil
+}
 
-	if !utils.HasFlag("--no-dns-reset") {
-		// Flush DNS cache
-		flushErr := utils.Spinner("Flushing DNS cache...", func() error {
-			cmd := exec.Command("sudo", "dscacheutil", "-flushcache")
-			var out bytes.Buffer
-			cmd.Stdout = &out
-			cmd.Stderr = &out
-			return cmd.Run()
-		})
-		if flushErr != nil {
-			return fmt.Errorf("failed to flush DNS cache: %s", flushErr.Error())
-		}
+// RestartApache validates the configuration and restarts Apache. When the
+// configuration is invalid, the changes of the current command are reverted
+// and Apache is left running as it was.
+func RestartApache() error {
+	if utils.IsDryRun() {
+		fmt.Println("DRY RUN: Would restart Apache server.")
+		return nil
+	}
 
-		// Reset mDNSResponder
-		resetErr := utils.Spinner("Resetting mDNSResponder...", func() error {
-			cmd := exec.Command("sudo", "killall", "-HUP", "mDNSResponder")
-			var out bytes.Buffer
-			cmd.Stdout = &out
-			cmd.Stderr = &out
-			return cmd.Run()
-		})
-		if resetErr != nil {
-			return fmt.Errorf("failed to reset mDNSResponder: %s", resetErr.Error())
-		}
+	// Never restart into a broken configuration
+	if err := testConfigOrRevert(); err != nil {
+		return err
+	}
 
-		utils.LogSuccess("DNS cache flushed and mDNSResponder reset successfully.")
-	} else {
-		utils.LogInfo("Skipping DNS cache flush and mDNSResponder reset as per user request.")
+	return restartApache()
+}
+
+// restartApache restarts the Apache service.
+func restartApache() error {
+	services, err := Services()
+	if err != nil {
+		return err
 	}
 
+	restartErr := utils.Spinner("Restarting Apache server...", func() error {
+		return services.Restart(ApacheService)
+	})
+
+	if restartErr != nil {
+		return fmt.Errorf("failed to restart Apache: %s", restartErr.Error())
+	}
+
+	fmt.Println("✔ Apache restarted successfully.")
 	return nil
 }
 
@@ -138,15 +241,16 @@ func VerifyApache() error {
 		if installErr := InstallApache(); installErr != nil {
 			return installErr
 		}
+	} else {
+		fmt.Println("✔ Apache is installed.")
 	}
 
-	// Check if Apache is running
+	// Check if Apache is running; it is started once the command is done
 	if err := CheckApacheRunning(); err != nil {
-		utils.LogWarning("Apache is not running. Attempting to restart...")
-
-		if restartErr := RestartApache(); restartErr != nil {
-			return fmt.Errorf("failed to restart Apache: %s", restartErr.Error())
-		}
+		utils.LogWarning("Apache is not running. It will be started once the changes are applied.")
+		RequestApacheRestart()
+	} else {
+		fmt.Println("✔ Apache is running.")
 	}
 
 	return nil
@@ -0,0 +1,240 @@
+package system
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
+)
+
+// ApacheStatus tells whether Apache is running, from its PID file and the ports
+// of its Listen directives rather than from the process list.
+type ApacheStatus struct {
+	PidFile string
+	PID     int       // The live server process, 0 when Apache is not running
+	Started time.Time // When the server wrote its PID file, zero when it is not running
+	Ports   []int     // The ports of the Listen directives
+	Bound   []int     // The ports accepting connections
+}
+
+// Running reports whether the PID file names a live Apache process.
+func (s *ApacheStatus) Running() bool {
+	return s.PID != 0
+}
+
+// Uptime returns for how long Apache has been running.
+func (s *ApacheStatus) Uptime() time.Duration {
+	if !s.Running() {
+		return 0
+	}
+	return time.Since(s.Started).Round(time.Second)
+}
+
+// Unbound returns the Listen ports that do not accept connections.
+func (s *ApacheStatus) Unbound() []int {
+	var unbound []int
+	for _, port := range s.Ports {
+		if !ContainsPort(s.Bound, port) {
+			unbound = append(unbound, port)
+		}
+	}
+	return unbound
+}
+
+// listenProbeTimeout is how long a Listen port has to accept a connection.
+const listenProbeTimeout = 500 * time.Millisecond
+
+// CheckApacheStatus reads the PidFile and Listen directives of httpd.conf and
+// the files it includes, checks that the PID is a live Apache process and
+// probes the ports.
+func CheckApacheStatus() (*ApacheStatus, error) {
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ApacheStatus{PidFile: apachePidFile(conf)}
+
+	if pid, started, err := readPidFile(status.PidFile); err == nil && isApacheProcess(pid) {
+		status.PID = pid
+		status.Started = started
+	}
+
+	for _, listen := range listenAddresses(conf) {
+		port := listen.port
+		if ContainsPort(status.Ports, port) {
+			continue
+		}
+		status.Ports = append(status.Ports, port)
+
+		if conn, err := net.DialTimeout("tcp", listen.String(), listenProbeTimeout); err == nil {
+			conn.Close()
+			status.Bound = append(status.Bound, port)
+		}
+	}
+	sort.Ints(status.Ports)
+	sort.Ints(status.Bound)
+
+	return status, nil
+}
+
+// apachePidFile returns the PID file of the configuration: the last PidFile
+// directive or the compiled-in default, relative to the ServerRoot.
+func apachePidFile(conf *apacheconf.File) string {
+	pidFile := "logs/httpd.pid"
+	if prefix := paths.Get().Prefix; prefix != "" {
+		pidFile = filepath.Join(prefix, "var/run/httpd/httpd.pid")
+	}
+
+	if directives := conf.Directives("PidFile"); len(directives) > 0 {
+		if args := directives[len(directives)-1].Args; len(args) > 0 {
+			pidFile = expandApacheVars(args[0], filepath.Dir(conf.Path))
+		}
+	}
+
+	if !filepath.IsAbs(pidFile) {
+		pidFile = filepath.Join(conf.ServerRoot(paths.Get().Root), pidFile)
+	}
+	return filepath.Join(paths.Get().Root, pidFile)
+}
+
+var apacheVarPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// expandApacheVars replaces the ${VAR} references with the environment, or with
+// the envvars file Debian's apache2ctl sources before starting the server.
+func expandApacheVars(value, confDir string) string {
+	if !apacheVarPattern.MatchString(value) {
+		return value
+	}
+
+	envvars := readEnvvars(filepath.Join(confDir, "envvars"))
+	return apacheVarPattern.ReplaceAllStringFunc(value, func(reference string) string {
+		name := apacheVarPattern.FindStringSubmatch(reference)[1]
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return envvars[name]
+	})
+}
+
+// readEnvvars reads the "export NAME=value" lines of an envvars file. The
+// $SUFFIX of multi-instance setups is dropped.
+func readEnvvars(path string) map[string]string {
+	vars := map[string]string{}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return vars
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export ")
+		name, value, found := strings.Cut(line, "=")
+		if !found || strings.HasPrefix(name, "#") {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		vars[name] = strings.ReplaceAll(value, "$SUFFIX", "")
+	}
+	return vars
+}
+
+// readPidFile returns the PID in the file and when the file was written.
+func readPidFile(path string) (int, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, time.Time{}, fmt.Errorf("%s does not hold a PID", path)
+	}
+	return pid, info.ModTime(), nil
+}
+
+// isApacheProcess reports whether the process is alive and, when ps can tell,
+// is Apache rather than an unrelated process that reused a stale PID.
+func isApacheProcess(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if err := process.Signal(syscall.Signal(0)); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return true
+	}
+	name := filepath.Base(strings.TrimSpace(string(out)))
+	return strings.Contains(name, "httpd") || strings.Contains(name, "apache2")
+}
+
+// listenAddress is the address of a Listen directive.
+type listenAddress struct {
+	host string
+	port int
+}
+
+// String returns the address to probe: a wildcard host is probed on localhost.
+func (l listenAddress) String() string {
+	host := l.host
+	if host == "" || host == "*" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(l.port))
+}
+
+// listenAddresses parses the Listen directives: "80", "127.0.0.1:8080",
+// "[::]:443" or "443 https".
+func listenAddresses(conf *apacheconf.File) []listenAddress {
+	var addresses []listenAddress
+	for _, directive := range conf.Directives("Listen") {
+		if len(directive.Args) == 0 {
+			continue
+		}
+
+		host, port := "", directive.Args[0]
+		if i := strings.LastIndex(port, ":"); i != -1 {
+			host, port = strings.Trim(port[:i], "[]"), port[i+1:]
+		}
+
+		number, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, listenAddress{host: host, port: number})
+	}
+	return addresses
+}
+
+// ContainsPort reports whether the port is in the list.
+func ContainsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
+)
+
+func TestListenAddresses(t *testing.T) {
+	conf, err := apacheconf.Parse("httpd.conf", []byte("Listen 80\nListen [::]:8443\nListen 127.0.0.1:80 http\nListen 443 https\nListen\nListen localhost:http\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []listenAddress{{"", 80}, {"::", 8443}, {"127.0.0.1", 80}, {"", 443}}
+	got := listenAddresses(conf)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("listenAddresses = %v, want %v", got, want)
+	}
+
+	// Wildcards are probed on localhost
+	for address, want := range map[listenAddress]string{
+		{"", 80}:           "localhost:80",
+		{"::", 8443}:       "localhost:8443",
+		{"127.0.0.1", 80}:  "127.0.0.1:80",
+		{"::1", 443}:       "[::1]:443",
+		{"0.0.0.0", 8080}:  "localhost:8080",
+		{"192.0.2.1", 443}: "192.0.2.1:443",
+	} {
+		if got := address.String(); got != want {
+			t.Errorf("%+v probes %s, want %s", address, got, want)
+		}
+	}
+}
+
+func TestReadPidFile(t *testing.T) {
+	dir := t.TempDir()
+	for content, want := range map[string]int{
+		"4242\n":   4242,
+		"  4242  ": 4242,
+		"":         0,
+		"0\n":      0,
+		"-1\n":     0,
+		"httpd\n":  0,
+	} {
+		path := filepath.Join(dir, "httpd.pid")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pid, _, err := readPidFile(path)
+		if pid != want || (want == 0) != (err != nil) {
+			t.Errorf("readPidFile(%q) = %d, %v; want %d", content, pid, err, want)
+		}
+	}
+
+	if _, _, err := readPidFile(filepath.Join(dir, "missing.pid")); err == nil {
+		t.Error("missing PID file: expected an error")
+	}
+}
+
+func TestCheckApacheStatus(t *testing.T) {
+	root := debianRoot(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	bound := listener.Addr().(*net.TCPAddr).Port
+
+	// A port nothing listens on: the listener's, once closed
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unbound := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	conf := fmt.Sprintf("PidFile /run/apache2/apache2.pid\nListen 127.0.0.1:%d http\nListen 127.0.0.1:%d\nListen 127.0.0.1:%d https\n", bound, unbound, bound)
+	if err := os.WriteFile(paths.Get().HttpdConf, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale PID file left by a crashed server
+	pidFile := filepath.Join(root, "run/apache2/apache2.pid")
+	if err := os.MkdirAll(filepath.Dir(pidFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pidFile, []byte("1073741823\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := CheckApacheStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PidFile != pidFile {
+		t.Errorf("PidFile = %s, want %s", status.PidFile, pidFile)
+	}
+	if status.Running() {
+		t.Errorf("a stale PID file reports Apache running with pid %d", status.PID)
+	}
+	if fmt.Sprint(status.Bound) != fmt.Sprint([]int{bound}) {
+		t.Errorf("Bound = %v, want [%d]", status.Bound, bound)
+	}
+	if fmt.Sprint(status.Unbound()) != fmt.Sprint([]int{unbound}) {
+		t.Errorf("Unbound = %v, want [%d]", status.Unbound(), unbound)
+	}
+	if len(status.Ports) != 2 {
+		t.Errorf("Ports = %v, want each port once", status.Ports)
+	}
+}
@@ -0,0 +1,204 @@
+package system
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// caCommonName is the name the local certificate authority shows in trust stores.
+const caCommonName = "localhost Root CA"
+
+// CertificateOptions returns the key type, validity and subject of the
+// certificates, from the cert_* settings.
+func CertificateOptions() certs.Options {
+	settings := paths.GetSettings()
+
+	opts := certs.DefaultOptions()
+	opts.KeyType = settings.CertKeyType
+	opts.Validity = settings.CertValidity
+	opts.Subject.Organization = []string{settings.CertOrganization}
+	return opts
+}
+
+// loadCA returns the certificate and key of the local certificate authority.
+func loadCA() (*x509.Certificate, crypto.Signer, error) {
+	cert, err := certs.LoadCertificate(paths.Get().CACertificateFile())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := certs.LoadKey(paths.Get().CAKeyFile())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !certs.MatchesKey(cert, key) {
+		return nil, nil, fmt.Errorf("%s does not match %s", paths.Get().CAKeyFile(), paths.Get().CACertificateFile())
+	}
+
+	return cert, key, nil
+}
+
+// EnsureCA creates the root certificate and key of the local certificate
+// authority, unless they already exist. Every site certificate is signed with
+// it, so trusting the root once makes every site trusted.
+func EnsureCA() error {
+	certFile, keyFile := paths.Get().CACertificateFile(), paths.Get().CAKeyFile()
+
+	if _, err := os.Stat(certFile); err == nil {
+		if _, _, err := loadCA(); err != nil {
+			return err
+		}
+		utils.LogSuccess("The local certificate authority already exists.")
+		return nil
+	}
+
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would create the local certificate authority in %s.", filepath.Dir(certFile)))
+		return nil
+	}
+
+	opts := CertificateOptions()
+	opts.Validity = certs.CAValidity
+
+	cert, key, err := certs.NewCA(caCommonName, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create the local certificate authority: %s", err.Error())
+	}
+
+	// Anyone holding the key can issue certificates the browser trusts, so it stays root's
+	if err := saveCertificate(certFile, keyFile, cert, key); err != nil {
+		return fmt.Errorf("failed to save the local certificate authority: %s", err.Error())
+	}
+
+	utils.LogSuccess(fmt.Sprintf("✔ Local certificate authority created:\n - Certificate: %s\n - Key: %s", certFile, keyFile))
+	return nil
+}
+
+// EnsureSiteCertificate makes sure the certificate of a site is valid, signed by
+// the local certificate authority and covers every name, issuing a new one
+// otherwise.
+func EnsureSiteCertificate(domain string, names []string) error {
+	certFile, keyFile := paths.Get().SiteCertificateFile(domain), paths.Get().SiteCertificateKeyFile(domain)
+
+	if cert, err := certs.LoadCertificate(certFile); err == nil {
+		problem := certificateProblem(cert, names)
+		if problem == "" {
+			utils.LogSuccess(fmt.Sprintf("The certificate of '%s' is valid until %s.", domain, cert.NotAfter.Format("2006-01-02")))
+			warnIfExpiring(Certificate{Domain: domain, Cert: cert})
+			return nil
+		}
+		utils.LogWarning(fmt.Sprintf("The certificate of '%s' %s. Issuing a new one...", domain, problem))
+	}
+
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would issue a certificate for %s.", strings.Join(names, ", ")))
+		return nil
+	}
+
+	if err := IssueCertificate(certFile, keyFile, names); err != nil {
+		return err
+	}
+
+	utils.LogSuccess(fmt.Sprintf("✔ Certificate for %s issued:\n - Certificate: %s\n - Key: %s", strings.Join(names, ", "), certFile, keyFile))
+	return nil
+}
+
+// certificateProblem explains why a certificate cannot be served for the names,
+// or returns an empty string when it can.
+func certificateProblem(cert *x509.Certificate, names []string) string {
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Sprintf("expired on %s", cert.NotAfter.Format("2006-01-02"))
+	}
+
+	ca, err := certs.LoadCertificate(paths.Get().CACertificateFile())
+	if err != nil || cert.CheckSignatureFrom(ca) != nil {
+		return "is not signed by the local certificate authority"
+	}
+
+	for _, name := range names {
+		if !certs.Covers(cert, name) {
+			return fmt.Sprintf("does not cover %s", name)
+		}
+	}
+	return ""
+}
+
+// IssueCertificate creates a key and a certificate for the names, signed by the
+// local certificate authority. The first name is the subject's common name.
+func IssueCertificate(certFile, keyFile string, names []string) error {
+	if err := EnsureCA(); err != nil {
+		return err
+	}
+
+	caCert, caKey, err := loadCA()
+	if err != nil {
+		return err
+	}
+
+	opts := CertificateOptions()
+
+	cert, key, err := certs.NewLeaf(caCert, caKey, names, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := saveCertificate(certFile, keyFile, cert, key); err != nil {
+		return fmt.Errorf("failed to save the certificate for %s: %s", names[0], err.Error())
+	}
+
+	return handKeyToApacheUser(keyFile)
+}
+
+// saveCertificate saves a certificate and its key, journaling both files and the
+// directories created for them so that a failed command removes them again.
+func saveCertificate(certFile, keyFile string, cert *x509.Certificate, key crypto.Signer) error {
+	for _, path := range []string{keyFile, certFile} {
+		if dir := filepath.Dir(path); !pathExists(dir) {
+			if err := utils.CreateDirectory(dir); err != nil {
+				return err
+			}
+		}
+		if err := utils.RecordFile(path); err != nil {
+			return err
+		}
+	}
+
+	return certs.Save(certFile, keyFile, cert, key)
+}
+
+// pathExists reports whether the file or directory exists.
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// handKeyToApacheUser gives a certificate key to the user Apache runs as:
+// Homebrew's Apache runs as the user who installed it rather than as root.
+func handKeyToApacheUser(keyFile string) error {
+	if paths.Get().Prefix == "" || os.Geteuid() != 0 {
+		return nil
+	}
+
+	account, err := user.Lookup(utils.GetOriginalUser())
+	if err != nil {
+		return fmt.Errorf("failed to look up the user running Apache: %s", err.Error())
+	}
+	uid, _ := strconv.Atoi(account.Uid)
+	gid, _ := strconv.Atoi(account.Gid)
+
+	if err := os.Chown(keyFile, uid, gid); err != nil {
+		return fmt.Errorf("failed to hand %s over to %s: %s", keyFile, account.Username, err.Error())
+	}
+	return nil
+}
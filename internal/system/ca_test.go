@@ -0,0 +1,97 @@
+package system
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// debianRoot initializes the paths with a Debian layout under a temporary root,
+// where the keys stay with the user running the tests.
+func debianRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("PATH", "")
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	if err := os.MkdirAll(filepath.Join(root, "etc/apache2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc/apache2/apache2.conf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+	utils.BackupDir = t.TempDir()
+	return root
+}
+
+func TestRevertRemovesIssuedCertificates(t *testing.T) {
+	debianRoot(t)
+	sslDir := paths.Get().SSLDir
+
+	if err := EnsureSiteCertificate("myproject.local", []string{"myproject.local"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(paths.Get().CAKeyFile()); err != nil {
+		t.Fatalf("the CA was not created: %v", err)
+	}
+
+	if _, err := utils.RevertChanges(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sslDir); !os.IsNotExist(err) {
+		t.Errorf("%s is left behind after the revert (%v)", sslDir, err)
+	}
+}
+
+func TestRevertRestoresReissuedCertificate(t *testing.T) {
+	debianRoot(t)
+	certFile, keyFile := paths.Get().SiteCertificateFile("myproject.local"), paths.Get().SiteCertificateKeyFile("myproject.local")
+
+	// Issued by an earlier command, without the name this one adds
+	caCert, caKey, err := certs.NewCA(caCommonName, CertificateOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Save(paths.Get().CACertificateFile(), paths.Get().CAKeyFile(), caCert, caKey); err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := certs.NewLeaf(caCert, caKey, []string{"myproject.local"}, CertificateOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Save(certFile, keyFile, cert, key); err != nil {
+		t.Fatal(err)
+	}
+	oldCert, _ := os.ReadFile(certFile)
+	oldKey, _ := os.ReadFile(keyFile)
+
+	if err := EnsureSiteCertificate("myproject.local", []string{"myproject.local", "api.myproject.local"}); err != nil {
+		t.Fatal(err)
+	}
+	if newCert, _ := os.ReadFile(certFile); bytes.Equal(newCert, oldCert) {
+		t.Fatal("the certificate was not reissued")
+	}
+
+	if _, err := utils.RevertChanges(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(certFile); !bytes.Equal(data, oldCert) {
+		t.Error("the revert did not restore the certificate")
+	}
+	if data, _ := os.ReadFile(keyFile); !bytes.Equal(data, oldKey) {
+		t.Error("the revert did not restore the key")
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key after the revert: %v, %v", info, err)
+	}
+	if _, err := os.Stat(paths.Get().CAKeyFile()); err != nil {
+		t.Errorf("the revert removed the CA it did not create: %v", err)
+	}
+}
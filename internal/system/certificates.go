@@ -0,0 +1,173 @@
+package system
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// Certificate is a certificate issued by the tool: the one of a site, or the
+// shared one of the default SSL virtual host.
+type Certificate struct {
+	Domain   string // Empty for the shared certificate
+	CertFile string
+	KeyFile  string
+	Cert     *x509.Certificate // Nil when the file could not be loaded
+	Err      error
+}
+
+// Label names the certificate in listings.
+func (c Certificate) Label() string {
+	if c.Domain == "" {
+		return "default (localhost)"
+	}
+	return c.Domain
+}
+
+// DaysLeft returns the number of whole days before the certificate expires,
+// negative once it expired.
+func (c Certificate) DaysLeft() int {
+	return int(math.Floor(time.Until(c.Cert.NotAfter).Hours() / 24))
+}
+
+// ExpiresWithin reports whether the certificate expires, or already expired,
+// within the window.
+func (c Certificate) ExpiresWithin(window time.Duration) bool {
+	return time.Until(c.Cert.NotAfter) < window
+}
+
+// Problem explains why the certificate cannot be served, or returns an empty
+// string when it can.
+func (c Certificate) Problem() string {
+	if c.Cert == nil {
+		return c.Err.Error()
+	}
+	return certificateProblem(c.Cert, nil)
+}
+
+// ExpiryWarning returns the warning printed for a certificate expiring within
+// the window, or an empty string.
+func (c Certificate) ExpiryWarning(window time.Duration) string {
+	if c.Cert == nil || !c.ExpiresWithin(window) {
+		return ""
+	}
+
+	renew := "localhost certs renew --all"
+	if c.Domain != "" {
+		renew = "localhost certs renew -domain=" + c.Domain
+	}
+
+	if c.DaysLeft() < 0 {
+		return fmt.Sprintf("The certificate of '%s' expired on %s. Run '%s'.", c.Label(), c.Cert.NotAfter.Format("2006-01-02"), renew)
+	}
+	return fmt.Sprintf("The certificate of '%s' expires in %d day(s), on %s. Run '%s'.", c.Label(), c.DaysLeft(), c.Cert.NotAfter.Format("2006-01-02"), renew)
+}
+
+// warnIfExpiring warns when the certificate expires within the cert_warn_days window.
+func warnIfExpiring(c Certificate) {
+	if warning := c.ExpiryWarning(CertificateWarnWindow()); warning != "" {
+		utils.LogWarning(warning)
+	}
+}
+
+// CertificateWarnWindow returns how long before expiry certificates are
+// reported, from the cert_warn_days setting.
+func CertificateWarnWindow() time.Duration {
+	return paths.GetSettings().CertWarnWindow
+}
+
+// loadCertificate loads the certificate in the file, keeping the error in the result.
+func loadCertificate(domain, certFile, keyFile string) Certificate {
+	cert, err := certs.LoadCertificate(certFile)
+	return Certificate{Domain: domain, CertFile: certFile, KeyFile: keyFile, Cert: cert, Err: err}
+}
+
+// SiteCertificate returns the certificate issued for the domain.
+func SiteCertificate(domain string) (Certificate, error) {
+	certFile := paths.Get().SiteCertificateFile(domain)
+	if _, err := os.Stat(certFile); err != nil {
+		return Certificate{}, fmt.Errorf("no certificate was issued for '%s'", domain)
+	}
+	return loadCertificate(domain, certFile, paths.Get().SiteCertificateKeyFile(domain)), nil
+}
+
+// RemoveSiteCertificate deletes the certificate and key issued for the domain.
+// Only a direct child of the site certificates directory is ever removed.
+func RemoveSiteCertificate(domain string) error {
+	sitesDir := filepath.Clean(paths.Get().SiteCertificatesDir())
+	dir := filepath.Dir(paths.Get().SiteCertificateFile(domain))
+	if filepath.Dir(dir) != sitesDir || dir == sitesDir {
+		return fmt.Errorf("refusing to remove %s: not a site certificate directory", dir)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %s", dir, err.Error())
+	}
+	return nil
+}
+
+// Certificates returns the shared certificate, when it exists, followed by the
+// certificate of every site, sorted by domain.
+func Certificates() ([]Certificate, error) {
+	var list []Certificate
+
+	if _, err := os.Stat(paths.Get().SSLCertificateFile()); err == nil {
+		list = append(list, loadCertificate("", paths.Get().SSLCertificateFile(), paths.Get().SSLCertificateKeyFile()))
+	}
+
+	sitesDir := paths.Get().SiteCertificatesDir()
+	entries, err := os.ReadDir(sitesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %s", sitesDir, err.Error())
+	}
+
+	var domains []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			domains = append(domains, entry.Name())
+		}
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		if site, err := SiteCertificate(domain); err == nil {
+			list = append(list, site)
+		}
+	}
+
+	return list, nil
+}
+
+// RenewCertificate issues a new certificate for the same names, with the
+// current cert_* settings, and requests a graceful reload of Apache.
+func RenewCertificate(c Certificate) error {
+	if c.Cert == nil {
+		return fmt.Errorf("cannot renew the certificate of '%s': %s", c.Label(), c.Err.Error())
+	}
+
+	names := certs.Names(c.Cert)
+	if len(names) == 0 {
+		return fmt.Errorf("the certificate of '%s' has no names to renew", c.Label())
+	}
+
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would issue a new certificate for %s.", strings.Join(names, ", ")))
+		return nil
+	}
+
+	if err := IssueCertificate(c.CertFile, c.KeyFile, names); err != nil {
+		return err
+	}
+	RequestApacheReload()
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package system
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// ConfigTestError is a configuration error reported by 'apachectl -t'.
+type ConfigTestError struct {
+	File      string // The file holding the faulty line, empty when the output names none
+	Line      int
+	Directive string
+	Message   string
+	Output    string // The full output of the test
+}
+
+func (e *ConfigTestError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("Apache configuration test failed: %s", e.Message)
+	}
+	if e.Directive == "" {
+		return fmt.Sprintf("Apache configuration test failed at %s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("Apache configuration test failed at %s:%d (%s): %s", e.File, e.Line, e.Directive, e.Message)
+}
+
+var (
+	syntaxErrorPattern    = regexp.MustCompile(`Syntax error on line (\d+) of ([^:]+):`)
+	invalidCommandPattern = regexp.MustCompile(`Invalid command '([^']+)'`)
+)
+
+// apachectl returns the control script (or the httpd binary) able to test the configuration.
+func apachectl() (string, error) {
+	if prefix := paths.Get().Prefix; prefix != "" {
+		// sudo usually drops the Homebrew bin directory from the PATH
+		candidate := filepath.Join(prefix, "bin", "apachectl")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	for _, name := range []string{"apachectl", "apache2ctl", "httpd"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("neither apachectl, apache2ctl nor httpd was found")
+}
+
+// ConfigTest runs 'apachectl -t' against httpd.conf. A failing test is reported
+// as a *ConfigTestError; any other error means the test could not run.
+func ConfigTest() error {
+	command, err := apachectl()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(command, "-t", "-f", paths.Get().HttpdConf)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*exec.ExitError); !ok {
+		return fmt.Errorf("failed to run %s: %s", command, err.Error())
+	}
+
+	return parseConfigTestOutput(string(out))
+}
+
+// parseConfigTestOutput extracts the faulty file, line and directive from the
+// output of a failed test. Errors in included files are reported as a chain of
+// "Syntax error on line N of FILE:" prefixes; the last one is the real culprit.
+func parseConfigTestOutput(output string) *ConfigTestError {
+	testErr := &ConfigTestError{Output: strings.TrimSpace(output)}
+
+	matches := syntaxErrorPattern.FindAllStringSubmatchIndex(output, -1)
+	if len(matches) == 0 {
+		testErr.Message = lastLine(output)
+		return testErr
+	}
+
+	last := matches[len(matches)-1]
+	testErr.Line, _ = strconv.Atoi(output[last[2]:last[3]])
+	testErr.File = output[last[4]:last[5]]
+	testErr.Message = firstLine(output[last[1]:])
+
+	if match := invalidCommandPattern.FindStringSubmatch(testErr.Message); match != nil {
+		testErr.Directive = match[1]
+	} else {
+		testErr.Directive = directiveAt(testErr.File, testErr.Line)
+	}
+
+	return testErr
+}
+
+// directiveAt returns the directive on a line of a configuration file.
+func directiveAt(path string, line int) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 1; scanner.Scan(); i++ {
+		if i != line {
+			continue
+		}
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(scanner.Text()), "<>/"))
+		if len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of the text.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// lastLine returns the last non-empty line of the text.
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// testConfigOrRevert validates the configuration before a restart. When it is
+// broken, every file changed by the current command is put back and the parsed
+// error is returned; Apache keeps running with its previous configuration.
+func testConfigOrRevert() error {
+	err := ConfigTest()
+	if err == nil {
+		fmt.Println("✔ Apache configuration test passed.")
+		return nil
+	}
+
+	var testErr *ConfigTestError
+	if !errors.As(err, &testErr) {
+		utils.LogWarning(fmt.Sprintf("Could not test the Apache configuration: %s", err))
+		return nil
+	}
+
+	utils.LogWarning("The Apache configuration is invalid. Reverting the changes made by this command...")
+	reverted, revertErr := utils.RevertChanges()
+	for _, path := range reverted {
+		fmt.Printf("✔ Reverted %s\n", path)
+	}
+	if revertErr != nil {
+		return fmt.Errorf("%s; %s", testErr.Error(), revertErr.Error())
+	}
+
+	return testErr
+}
@@ -0,0 +1,141 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func TestParseConfigTestOutput(t *testing.T) {
+	vhost := filepath.Join(t.TempDir(), "myproject.local.conf")
+	if err := os.WriteFile(vhost, []byte("<VirtualHost *:80>\n    ServerName myproject.local\n    DocumentRoot /Users/dev/Sites/missing\n</VirtualHost>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   ConfigTestError
+	}{
+		{
+			"invalid command",
+			"AH00526: Syntax error on line 12 of /opt/homebrew/etc/httpd/extra/vhosts/myproject.local.conf:\n" +
+				"Invalid command 'SSLEngine', perhaps misspelled or defined by a module not included in the server configuration\n",
+			ConfigTestError{
+				File:      "/opt/homebrew/etc/httpd/extra/vhosts/myproject.local.conf",
+				Line:      12,
+				Directive: "SSLEngine",
+				Message:   "Invalid command 'SSLEngine', perhaps misspelled or defined by a module not included in the server configuration",
+			},
+		},
+		{
+			// The error is in an included file: the last location is the culprit
+			"included file",
+			"AH00526: Syntax error on line 512 of /opt/homebrew/etc/httpd/httpd.conf:\n" +
+				"Syntax error on line 3 of " + vhost + ":\n" +
+				"DocumentRoot '/Users/dev/Sites/missing' is not a directory, or is not readable\n",
+			ConfigTestError{
+				File:      vhost,
+				Line:      3,
+				Directive: "DocumentRoot",
+				Message:   "DocumentRoot '/Users/dev/Sites/missing' is not a directory, or is not readable",
+			},
+		},
+		{
+			"message on the same line",
+			"httpd: Syntax error on line 66 of /opt/homebrew/etc/httpd/httpd.conf: Cannot load lib/httpd/modules/mod_foo.so into server: dlopen(/opt/homebrew/lib/httpd/modules/mod_foo.so, 0x000A): tried: '/opt/homebrew/lib/httpd/modules/mod_foo.so' (no such file)\n",
+			ConfigTestError{
+				File:    "/opt/homebrew/etc/httpd/httpd.conf",
+				Line:    66,
+				Message: "Cannot load lib/httpd/modules/mod_foo.so into server: dlopen(/opt/homebrew/lib/httpd/modules/mod_foo.so, 0x000A): tried: '/opt/homebrew/lib/httpd/modules/mod_foo.so' (no such file)",
+			},
+		},
+		{
+			"no location",
+			"AH00558: apache2: Could not reliably determine the server's fully qualified domain name\n" +
+				"AH00016: Configuration Failed\n",
+			ConfigTestError{Message: "AH00016: Configuration Failed"},
+		},
+	} {
+		got := parseConfigTestOutput(tc.output)
+		tc.want.Output = got.Output
+		if *got != tc.want {
+			t.Errorf("%s: parseConfigTestOutput = %+v, want %+v", tc.name, *got, tc.want)
+		}
+	}
+}
+
+// fakeApachectl installs an apachectl under the Homebrew prefix that prints
+// the output and exits with the status.
+func fakeApachectl(t *testing.T, output string, status int) {
+	t.Helper()
+	quoted := "'" + strings.ReplaceAll(output, "'", `'\''`) + "'"
+	script := fmt.Sprintf("#!/bin/sh\nprintf '%%s' %s >&2\nexit %d\n", quoted, status)
+	path := filepath.Join(paths.Get().Prefix, "bin", "apachectl")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTestConfigOrRevert(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		output     string
+		status     int
+		noCommand  bool
+		wantErr    bool
+		wantRevert bool
+	}{
+		{"valid", "Syntax OK\n", 0, false, false, false},
+		{"invalid", "AH00526: Syntax error on line 2 of /etc/httpd/extra/vhosts/myproject.local.conf:\nInvalid command 'SSLEngine', perhaps misspelled\n", 1, false, true, true},
+		// The change is kept: Apache itself reports the problem on restart
+		{"untestable", "", 0, true, false, false},
+	} {
+		root := t.TempDir()
+		t.Setenv("PATH", "")
+		t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+		t.Setenv("LOCALHOST_PREFIX", filepath.Join(root, "homebrew"))
+		if err := paths.Init(root); err != nil {
+			t.Fatal(err)
+		}
+		utils.BackupDir = t.TempDir()
+		if !tc.noCommand {
+			fakeApachectl(t, tc.output, tc.status)
+		}
+
+		conf := filepath.Join(root, "httpd.conf")
+		if err := os.WriteFile(conf, []byte("Listen 8080\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := utils.WriteFileAtomic(conf, []byte("Listen 8080\nSSLEngine on\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := testConfigOrRevert()
+		var testErr *ConfigTestError
+		if tc.wantErr && (!errors.As(err, &testErr) || testErr.Directive != "SSLEngine" || testErr.Line != 2) {
+			t.Errorf("%s: testConfigOrRevert = %v, want the parsed configuration error", tc.name, err)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: testConfigOrRevert = %v", tc.name, err)
+		}
+
+		want := "Listen 8080\nSSLEngine on\n"
+		if tc.wantRevert {
+			want = "Listen 8080\n"
+		}
+		if data, _ := os.ReadFile(conf); string(data) != want {
+			t.Errorf("%s: httpd.conf is %q, want %q", tc.name, data, want)
+		}
+		utils.RevertChanges()
+	}
+}
@@ -0,0 +1,98 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// DNSFlushError is a failure to flush the DNS cache. Returned by
+// ApplyApacheChanges, it means Apache already took the changes.
+type DNSFlushError struct {
+	Err error
+}
+
+func (e *DNSFlushError) Error() string {
+	return e.Err.Error()
+}
+
+// FlushDNSCache flushes the system DNS cache so that hosts file and resolver changes
+// take effect immediately. It is skipped when the --no-dns-reset flag is given.
+// Failures are reported as a *DNSFlushError.
+func FlushDNSCache() error {
+	if utils.HasFlag("--no-dns-reset") {
+		utils.LogInfo("Skipping DNS cache flush and mDNSResponder reset as per user request.")
+		return nil
+	}
+
+	if utils.IsDryRun() {
+		fmt.Println("DRY RUN: Would flush the DNS cache.")
+		return nil
+	}
+
+	if runtime.GOOS != "darwin" {
+		flushErr := utils.Spinner("Flushing DNS cache...", func() error {
+			cmd := exec.Command("resolvectl", "flush-caches")
+			var out bytes.Buffer
+			cmd.Stdout = &out
+			cmd.Stderr = &out
+			return cmd.Run()
+		})
+		if flushErr != nil {
+			return &DNSFlushError{fmt.Errorf("failed to flush DNS cache: %s", flushErr.Error())}
+		}
+
+		utils.LogSuccess("DNS cache flushed successfully.")
+		return nil
+	}
+
+	// Flush DNS cache
+	flushErr := utils.Spinner("Flushing DNS cache...", func() error {
+		cmd := exec.Command("sudo", "dscacheutil", "-flushcache")
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		return cmd.Run()
+	})
+	if flushErr != nil {
+		return &DNSFlushError{fmt.Errorf("failed to flush DNS cache: %s", flushErr.Error())}
+	}
+
+	// Reset mDNSResponder
+	resetErr := utils.Spinner("Resetting mDNSResponder...", func() error {
+		cmd := exec.Command("sudo", "killall", "-HUP", "mDNSResponder")
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		return cmd.Run()
+	})
+	if resetErr != nil {
+		return &DNSFlushError{fmt.Errorf("failed to reset mDNSResponder: %s", resetErr.Error())}
+	}
+
+	utils.LogSuccess("DNS cache flushed and mDNSResponder reset successfully.")
+	return nil
+}
+
+// RestartSystemdResolved restarts systemd-resolved so that it picks up new drop-in files.
+func RestartSystemdResolved() error {
+	if utils.IsDryRun() {
+		fmt.Println("DRY RUN: Would restart systemd-resolved.")
+		return nil
+	}
+
+	cmd := exec.Command("systemctl", "restart", "systemd-resolved")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to restart systemd-resolved: %s", out.String())
+	}
+
+	fmt.Println("✔ systemd-resolved restarted successfully.")
+	return nil
+}
@@ -0,0 +1,37 @@
+package system
+
+import (
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// http2Modules are the modules sites offering HTTP/2 need.
+var http2Modules = []apacheModule{
+	{"http2_module", "mod_http2.so"},
+}
+
+// EnableHTTP2ModuleInHttpdConf makes sure httpd.conf loads mod_http2. Apache
+// refuses HTTP/2 under the prefork MPM, which the PHP module needs, so it warns
+// when prefork is loaded.
+func EnableHTTP2ModuleInHttpdConf() error {
+	if utils.IsDryRun() {
+		utils.LogInfo("DRY RUN: Would enable the HTTP/2 module in Apache configuration.")
+		return nil
+	}
+
+	utils.LogInfo("Enabling the HTTP/2 module in Apache configuration...")
+
+	if err := enableModulesInHttpdConf(http2Modules); err != nil {
+		return err
+	}
+
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
+	if err != nil {
+		return err
+	}
+	if conf.ModuleLoaded("mpm_prefork_module") {
+		utils.LogWarning("Apache runs the prefork MPM, which does not support HTTP/2: the site is served over HTTP/1.1 until httpd.conf loads mpm_event_module instead (PHP sites then need -php to run through PHP-FPM).")
+	}
+	return nil
+}
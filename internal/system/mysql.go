@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net"
 	"os/exec"
-	"strings"
+	"time"
 
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
@@ -19,34 +20,45 @@ func CheckMySQLInstalled() error {
 
 	err := cmd.Run()
 	if err != nil {
-		return errors.New("MySQL is not installed or not accessible. Install it using Homebrew: 'brew install mysql'")
+		return fmt.Errorf("MySQL is not installed or not accessible. Install it with '%s'", InstallCommand(MySQLService))
 	}
 
-	// MySQL is installed
-	fmt.Println("✔ MySQL is installed.")
 	return nil
 }
 
 // CheckMySQLRunning verifies if MySQL is currently running.
 func CheckMySQLRunning() error {
-	cmd := exec.Command("brew", "services", "list")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	services, err := Services()
+	if err != nil {
+		return err
+	}
 
-	err := utils.RunAsOriginalUser(cmd)
+	status, err := services.Status(MySQLService)
 	if err != nil {
 		return fmt.Errorf("failed to check running services: %s", err.Error())
 	}
 
-	if strings.Contains(out.String(), "mysql") && strings.Contains(out.String(), "started") {
-		fmt.Println("✔ MySQL is running.")
+	if status.Running() {
 		return nil
 	}
 
 	return errors.New("MySQL is not running")
 }
 
+// mysqlAddress is where a local MySQL server accepts TCP connections.
+const mysqlAddress = "127.0.0.1:3306"
+
+// CheckMySQLReachable verifies that the MySQL server accepts TCP connections.
+func CheckMySQLReachable() error {
+	conn, err := net.DialTimeout("tcp", mysqlAddress, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("MySQL is not reachable on %s: %s", mysqlAddress, err.Error())
+	}
+	conn.Close()
+
+	return nil
+}
+
 // InstallMySQL attempts to install MySQL using Homebrew.
 func InstallMySQL() error {
 	if utils.IsDryRun() {
@@ -70,21 +82,20 @@ func InstallMySQL() error {
 	return nil
 }
 
-// RestartMySQL attempts to restart MySQL using Homebrew services.
+// RestartMySQL attempts to restart MySQL using the service manager.
 func RestartMySQL() error {
 	if utils.IsDryRun() {
 		utils.LogInfo("DRY RUN: Would restart MySQL.")
 		return nil
 	}
 
-	cmd := exec.Command("brew", "services", "restart", "mysql")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-
-	err := utils.RunAsOriginalUser(cmd)
+	services, err := Services()
 	if err != nil {
-		return fmt.Errorf("failed to restart MySQL: %s", out.String())
+		return err
+	}
+
+	if err := services.Restart(MySQLService); err != nil {
+		return fmt.Errorf("failed to restart MySQL: %s", err.Error())
 	}
 
 	utils.LogSuccess("MySQL restarted successfully.")
@@ -103,6 +114,8 @@ func VerifyMySQL() error {
 		if installErr := InstallMySQL(); installErr != nil {
 			return installErr
 		}
+	} else {
+		fmt.Println("✔ MySQL is installed.")
 	}
 
 	// Check if MySQL is running
@@ -112,6 +125,8 @@ func VerifyMySQL() error {
 		if restartErr := RestartMySQL(); restartErr != nil {
 			return fmt.Errorf("failed to restart MySQL: %s", restartErr.Error())
 		}
+	} else {
+		fmt.Println("✔ MySQL is running.")
 	}
 
 	return nil
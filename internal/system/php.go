@@ -1,20 +1,17 @@
 package system
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
 
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
-// HttpdConfPath defines the path to the Apache main configuration file.
-const HttpdConfPath = "/opt/homebrew/etc/httpd/httpd.conf"
-
 // CheckPHPInstalled verifies if PHP is installed on the system.
 func CheckPHPInstalled() error {
 	cmd := exec.Command("php", "-v")
@@ -27,18 +24,11 @@ func CheckPHPInstalled() error {
 		return errors.New("PHP is not installed or not accessible. Install it using Homebrew: 'brew install php'")
 	}
 
-	// PHP is installed
-	utils.LogSuccess("PHP is installed.")
 	return nil
 }
 
 // CheckPHPWorking verifies if PHP can execute a basic script.
 func CheckPHPWorking() error {
-	if utils.IsDryRun() {
-		utils.LogInfo("DRY RUN: Would check if PHP is working correctly.")
-		return nil
-	}
-
 	script := `echo "PHP is working!";`
 	cmd := exec.Command("php", "-r", script)
 	var out bytes.Buffer
@@ -52,7 +42,6 @@ func CheckPHPWorking() error {
 
 	output := out.String()
 	if strings.Contains(output, "PHP is working!") {
-		utils.LogSuccess("PHP is working correctly.")
 		return nil
 	}
 
@@ -99,18 +88,21 @@ func VerifyPHP() error {
 		if err := EnablePHPModuleInHttpdConf(); err != nil {
 			return err
 		}
-
-		// Restart Apache to apply changes
-		if restartErr := RestartApache(); restartErr != nil {
-			return fmt.Errorf("failed to restart Apache after enabling PHP: %s", restartErr.Error())
-		}
+	} else {
+		utils.LogSuccess("PHP is installed.")
 	}
 
 	// Check if PHP is working
+	if utils.IsDryRun() {
+		utils.LogInfo("DRY RUN: Would check if PHP is working correctly.")
+		return nil
+	}
+
 	if err := CheckPHPWorking(); err != nil {
 		return err
 	}
 
+	utils.LogSuccess("PHP is working correctly.")
 	return nil
 }
 
@@ -122,90 +114,90 @@ func EnablePHPModuleInHttpdConf() error {
 
 	utils.LogInfo("Enabling PHP module in Apache configuration...")
 
-	file, err := os.Open(HttpdConfPath)
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
 	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf: %s", err.Error())
+		return err
 	}
-	defer file.Close()
-
-	var lines []string
-	phpModuleLoaded := false
-	setHandlerAdded := false
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Check if the PHP module is already loaded
-		if strings.Contains(line, "LoadModule php") && !strings.HasPrefix(line, "#") {
-			phpModuleLoaded = true
-		}
-
-		// Check if the SetHandler directive already exists
-		if strings.Contains(line, "SetHandler application/x-httpd-php") {
-			setHandlerAdded = true
-		}
+	changed := false
 
-		// Uncomment the PHP module loading line if it's commented
-		if strings.Contains(line, "LoadModule php") && strings.HasPrefix(line, "#") {
-			line = strings.TrimPrefix(line, "#")
-			phpModuleLoaded = true
+	// Load the PHP module; PHP 7 and older register it under a versioned name
+	if !conf.ModuleLoaded("php7_module") && !conf.ModuleLoaded("php5_module") && !conf.ModuleLoaded("php_module") {
+		if paths.Get().PHPModule == "" {
+			return fmt.Errorf("the PHP module is not loaded; install and enable it with your package manager")
 		}
+		conf.EnableModule("php_module", paths.Get().PHPModule)
+		changed = true
+		RequestApacheRestart()
 
-		lines = append(lines, line)
+		utils.LogSuccess("✔ Enabled the PHP module in httpd.conf.")
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read httpd.conf: %s", err.Error())
-	}
+	// Hand .php files to PHP, next to the DirectoryIndex settings
+	dirModule := conf.FindSection("IfModule", "dir_module")
+	if !hasPHPHandler(conf) {
+		handler := apacheconf.NewSection("FilesMatch", `\.php$`)
+		handler.Append(apacheconf.NewDirective("SetHandler", "application/x-httpd-php"))
 
-	// Add the PHP module loading line if not found
-	if !phpModuleLoaded {
-		lines = append([]string{"LoadModule php_module /opt/homebrew/opt/php/lib/httpd/modules/libphp.so"}, lines...)
+		if dirModule != nil && dirModule.Parent == conf.Root {
+			dirModule.InsertAfter(apacheconf.NewBlank(), handler)
+		} else {
+			conf.Root.Append(apacheconf.NewBlank(), handler)
+		}
+		changed = true
 
-		utils.LogSuccess("✔ Added PHP module loading line to httpd.conf.")
+		utils.LogSuccess("Added SetHandler directive to httpd.conf.")
 	}
 
-	// Add the SetHandler directive if not found
-	if !setHandlerAdded {
-		lines = append(lines, "<FilesMatch \\.php$>")
-		lines = append(lines, "    SetHandler application/x-httpd-php")
-		lines = append(lines, "</FilesMatch>")
-
-		utils.LogSuccess("Added SetHandler directive to httpd.conf.")
+	// Serve index.php before index.html
+	if dirModule == nil {
+		dirModule = apacheconf.NewSection("IfModule", "dir_module")
+		conf.Root.Append(apacheconf.NewBlank(), dirModule)
 	}
 
-	// Add the index.php file to the httpd.conf in <IfModule dir_module>DirectoryIndex index.html</IfModule>
-	indexPhpAdded := false
-	for _, line := range lines {
-		if strings.Contains(line, "index.php") {
-			indexPhpAdded = true
-			break
+	directive := dirModule.Find("DirectoryIndex")
+	if directive != nil && containsArg(directive.Args, "index.php") {
+		utils.LogSuccess("index.php file already exists in httpd.conf.")
+	} else {
+		index := []string{"index.php", "index.html"}
+		if directive != nil {
+			index = append([]string{"index.php"}, directive.Args...)
 		}
-	}
-	if !indexPhpAdded {
-		lines = append(lines, "<IfModule dir_module>")
-		lines = append(lines, "    DirectoryIndex index.php index.html")
-		lines = append(lines, "</IfModule>")
+		conf.SetDirective(dirModule, "DirectoryIndex", index...)
+		changed = true
 
 		utils.LogSuccess("Added index.php file to httpd.conf.")
-	} else {
-		utils.LogSuccess("index.php file already exists in httpd.conf.")
 	}
 
 	// Write the updated content back to httpd.conf
-	file, err = os.OpenFile(HttpdConfPath, os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf for writing: %s", err.Error())
-	}
-	defer file.Close()
-
-	for _, line := range lines {
-		if _, err := file.WriteString(line + "\n"); err != nil {
+	if changed {
+		if err := conf.Save(); err != nil {
 			return fmt.Errorf("failed to write to httpd.conf: %s", err.Error())
 		}
+		RequestApacheReload()
 	}
 
 	utils.LogSuccess("PHP module and handler enabled in httpd.conf.")
 	return nil
 }
+
+// hasPHPHandler reports whether httpd.conf already hands files to the PHP module.
+func hasPHPHandler(conf *apacheconf.File) bool {
+	for _, name := range []string{"SetHandler", "AddHandler", "AddType"} {
+		for _, directive := range conf.Directives(name) {
+			if containsArg(directive.Args, "application/x-httpd-php") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsArg reports whether the directive arguments include the value.
+func containsArg(args []string, value string) bool {
+	for _, arg := range args {
+		if arg == value {
+			return true
+		}
+	}
+	return false
+}
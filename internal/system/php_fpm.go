@@ -0,0 +1,330 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// PHPFPM is the PHP-FPM service of one PHP version.
+type PHPFPM struct {
+	Version  string // Major and minor version, e.g. "8.1"
+	Formula  string // Homebrew formula ("php@8.1" or "php"), empty for distribution packages
+	Service  string // The service to start
+	PoolConf string // The pool configuration holding the listen address
+	Socket   string // The Unix socket the vhosts send PHP requests to
+}
+
+var phpVersionPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// ValidatePHPVersion checks that the version has the X.Y form.
+func ValidatePHPVersion(version string) error {
+	if !phpVersionPattern.MatchString(version) {
+		return fmt.Errorf("invalid PHP version '%s', expected a version like 8.3", version)
+	}
+	return nil
+}
+
+// InstalledPHPVersions returns the installed PHP versions mapped to the Homebrew
+// formula providing them. With distribution packages, the versions with a
+// PHP-FPM configuration are returned and the formula is empty.
+func InstalledPHPVersions() (map[string]string, error) {
+	versions := map[string]string{}
+
+	if paths.Get().Prefix == "" {
+		dirs, _ := filepath.Glob(filepath.Join(paths.Get().Root, "/etc/php/*/fpm"))
+		for _, dir := range dirs {
+			versions[filepath.Base(filepath.Dir(dir))] = ""
+		}
+		return versions, nil
+	}
+
+	cmd := exec.Command("brew", "list", "--formula", "--versions")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := utils.RunAsOriginalUser(cmd); err != nil {
+		return nil, fmt.Errorf("failed to list the Homebrew formulae: %s", out.String())
+	}
+
+	// Lines look like "php 8.3.4" or "php@7.4 7.4.33_6"
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(fields[0], "php@"):
+			versions[strings.TrimPrefix(fields[0], "php@")] = fields[0]
+		case fields[0] == "php":
+			parts := strings.SplitN(fields[len(fields)-1], ".", 3)
+			if len(parts) >= 2 {
+				// A versioned formula wins over the unversioned one
+				if _, found := versions[parts[0]+"."+parts[1]]; !found {
+					versions[parts[0]+"."+parts[1]] = "php"
+				}
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// sortedVersions returns the keys of the versions map, sorted.
+func sortedVersions(versions map[string]string) []string {
+	var list []string
+	for version := range versions {
+		list = append(list, version)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// LookupPHPFPM returns the PHP-FPM service of an installed PHP version.
+func LookupPHPFPM(version string) (*PHPFPM, error) {
+	versions, err := InstalledPHPVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	formula, found := versions[version]
+	if !found {
+		return nil, fmt.Errorf("PHP %s is not installed (installed: %s)", version, strings.Join(sortedVersions(versions), ", "))
+	}
+
+	return phpFPMFor(version, formula), nil
+}
+
+// phpFPMFor returns the locations of the PHP-FPM service of the version.
+func phpFPMFor(version, formula string) *PHPFPM {
+	root := paths.Get().Root
+
+	// Debian/Ubuntu packages already listen on a socket per version
+	if paths.Get().Prefix == "" {
+		return &PHPFPM{
+			Version:  version,
+			Service:  fmt.Sprintf("php%s-fpm", version),
+			PoolConf: filepath.Join(root, "/etc/php", version, "fpm/pool.d/www.conf"),
+			Socket:   filepath.Join(root, "/run/php", fmt.Sprintf("php%s-fpm.sock", version)),
+		}
+	}
+
+	// Homebrew versions all listen on 127.0.0.1:9000 by default, so each one gets its own socket
+	prefix := filepath.Join(root, paths.Get().Prefix)
+	return &PHPFPM{
+		Version:  version,
+		Formula:  formula,
+		Service:  formula,
+		PoolConf: filepath.Join(prefix, "etc/php", version, "php-fpm.d/www.conf"),
+		Socket:   filepath.Join(prefix, "var/run", fmt.Sprintf("php%s-fpm.sock", version)),
+	}
+}
+
+// InstallPHPVersion attempts to install the php@X.Y formula using Homebrew.
+func InstallPHPVersion(version string) error {
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would install php@%s using Homebrew.", version))
+		return nil
+	}
+
+	if paths.Get().Prefix == "" {
+		return fmt.Errorf("PHP %s is not installed. Install the php%s-fpm package with your package manager", version, version)
+	}
+
+	utils.LogWarning(fmt.Sprintf("PHP %s is not installed. Attempting to install it using Homebrew...", version))
+
+	cmd := exec.Command("brew", "install", "php@"+version)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := utils.RunAsOriginalUser(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to install php@%s: %s (older versions need 'brew tap shivammathur/php')", version, out.String())
+	}
+
+	utils.LogSuccess(fmt.Sprintf("PHP %s installed successfully.", version))
+	return nil
+}
+
+// apacheAccount returns the user and group the Apache workers run as: those of
+// the layout, or the user who installed Homebrew's Apache, which runs as them.
+func apacheAccount() (string, string, error) {
+	if paths.Get().ApacheUser != "" {
+		group := paths.Get().ApacheGroup
+		if group == "" {
+			group = paths.Get().ApacheUser
+		}
+		return paths.Get().ApacheUser, group, nil
+	}
+
+	account, err := user.Lookup(utils.GetOriginalUser())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up the user running Apache: %s", err.Error())
+	}
+	if paths.Get().ApacheGroup != "" {
+		return account.Username, paths.Get().ApacheGroup, nil
+	}
+	group, err := user.LookupGroupId(account.Gid)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up the group of %s: %s", account.Username, err.Error())
+	}
+	return account.Username, group.Name, nil
+}
+
+// ConfigureSocket makes the pool listen on the version's own Unix socket, which
+// only Apache's user or group may use (mode 0660, as Debian sets it up). It
+// reports whether the configuration changed.
+func (f *PHPFPM) ConfigureSocket() (bool, error) {
+	owner, group, err := apacheAccount()
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(f.PoolConf)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %s", f.PoolConf, err.Error())
+	}
+	defer file.Close()
+
+	// The listen line is only set when active; the others are uncommented too
+	settings := []struct{ key, value string }{
+		{"listen", f.Socket},
+		{"listen.owner", owner},
+		{"listen.group", group},
+		{"listen.mode", "0660"},
+	}
+	found := map[string]bool{}
+	changed := false
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		name, commented := strings.CutPrefix(key, ";")
+		name = strings.TrimSpace(name)
+
+		for _, setting := range settings {
+			if name != setting.key || commented && (setting.key == "listen" || found[setting.key]) {
+				continue
+			}
+
+			found[setting.key] = true
+			if commented || value != setting.value {
+				line = setting.key + " = " + setting.value
+				changed = true
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read %s: %s", f.PoolConf, err.Error())
+	}
+
+	for _, setting := range settings {
+		if !found[setting.key] {
+			lines = append(lines, setting.key+" = "+setting.value)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	if err := utils.CreateDirectory(filepath.Dir(f.Socket)); err != nil {
+		return false, err
+	}
+
+	if err := utils.WriteLinesAtomic(f.PoolConf, lines, 0644); err != nil {
+		return false, fmt.Errorf("failed to write to %s: %s", f.PoolConf, err.Error())
+	}
+
+	utils.LogSuccess(fmt.Sprintf("✔ PHP %s FPM now listens on %s, for %s:%s.", f.Version, f.Socket, owner, group))
+	return true, nil
+}
+
+// Restart restarts the PHP-FPM service.
+func (f *PHPFPM) Restart() error {
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would restart %s.", f.Service))
+		return nil
+	}
+
+	services, err := Services()
+	if err != nil {
+		return err
+	}
+
+	if err := services.Restart(Service{Formula: f.Formula, Units: []string{f.Service}}); err != nil {
+		return fmt.Errorf("failed to restart %s: %s", f.Service, err.Error())
+	}
+
+	utils.LogSuccess(fmt.Sprintf("%s restarted successfully.", f.Service))
+	return nil
+}
+
+// VerifyPHPFPM ensures the PHP version is installed, listens on its own socket
+// and that its FPM service is running.
+func VerifyPHPFPM(version string) (*PHPFPM, error) {
+	utils.LogInfo(fmt.Sprintf("Checking PHP %s FPM setup...", version))
+
+	fpm, err := LookupPHPFPM(version)
+	if err != nil {
+		fmt.Println(err)
+
+		if installErr := InstallPHPVersion(version); installErr != nil {
+			return nil, installErr
+		}
+
+		if utils.IsDryRun() {
+			return phpFPMFor(version, "php@"+version), nil
+		}
+
+		if fpm, err = LookupPHPFPM(version); err != nil {
+			return nil, err
+		}
+	} else {
+		utils.LogSuccess(fmt.Sprintf("PHP %s is installed.", version))
+	}
+
+	if utils.IsDryRun() {
+		utils.LogInfo(fmt.Sprintf("DRY RUN: Would make PHP %s FPM listen on %s.", version, fpm.Socket))
+		return fpm, nil
+	}
+
+	// Distribution packages come with their own socket
+	changed := false
+	if fpm.Formula != "" {
+		if changed, err = fpm.ConfigureSocket(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Start the service, or restart it to pick up the new socket
+	if _, err := os.Stat(fpm.Socket); changed || err != nil {
+		if err := fpm.Restart(); err != nil {
+			return nil, err
+		}
+	} else {
+		utils.LogSuccess(fmt.Sprintf("PHP %s FPM is listening on %s.", version, fpm.Socket))
+	}
+
+	return fpm, nil
+}
@@ -0,0 +1,74 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+func TestConfigureSocket(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	t.Setenv("LOCALHOST_APACHE_USER", "www-data")
+	t.Setenv("LOCALHOST_APACHE_GROUP", "www-data")
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+	utils.BackupDir = t.TempDir()
+
+	// The pool of a Homebrew PHP, and one a previous version opened to everyone
+	for name, pool := range map[string]string{
+		"homebrew": "[www]\nuser = _www\nlisten = 127.0.0.1:9000\n;listen.owner = _www\n;listen.group = _www\n; listen.mode = 0660\npm = dynamic\n",
+		"world":    "[www]\nlisten = /tmp/old.sock\nlisten.mode = 0666\n",
+	} {
+		fpm := &PHPFPM{
+			Version:  "8.3",
+			PoolConf: filepath.Join(root, name, "www.conf"),
+			Socket:   filepath.Join(root, name, "run/php8.3-fpm.sock"),
+		}
+		if err := os.MkdirAll(filepath.Dir(fpm.PoolConf), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fpm.PoolConf, []byte(pool), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		changed, err := fpm.ConfigureSocket()
+		if err != nil || !changed {
+			t.Fatalf("%s: ConfigureSocket = %v, %v", name, changed, err)
+		}
+
+		data, err := os.ReadFile(fpm.PoolConf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := map[string][]string{}
+		for _, line := range strings.Split(string(data), "\n") {
+			if key, value, found := strings.Cut(line, " = "); found && !strings.HasPrefix(key, ";") {
+				values[key] = append(values[key], value)
+			}
+		}
+		for key, want := range map[string]string{
+			"listen":       fpm.Socket,
+			"listen.owner": "www-data",
+			"listen.group": "www-data",
+			"listen.mode":  "0660",
+		} {
+			if len(values[key]) != 1 || values[key][0] != want {
+				t.Errorf("%s: %s = %v, want %s\n%s", name, key, values[key], want, data)
+			}
+		}
+		if strings.Contains(string(data), "0666") {
+			t.Errorf("%s: the socket is still open to everyone:\n%s", name, data)
+		}
+
+		// A configured pool is left alone
+		if changed, err := fpm.ConfigureSocket(); err != nil || changed {
+			t.Errorf("%s: second ConfigureSocket = %v, %v", name, changed, err)
+		}
+	}
+}
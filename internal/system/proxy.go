@@ -0,0 +1,86 @@
+package system
+
+import (
+	"fmt"
+
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// apacheModule is a module httpd.conf can load, with its file in the modules directory.
+type apacheModule struct {
+	name string
+	file string
+}
+
+// proxyModules are the modules reverse proxy sites need: the proxy itself, the
+// HTTP backend, WebSocket upgrades and the X-Forwarded-* request headers.
+var proxyModules = []apacheModule{
+	{"proxy_module", "mod_proxy.so"},
+	{"proxy_http_module", "mod_proxy_http.so"},
+	{"proxy_wstunnel_module", "mod_proxy_wstunnel.so"},
+	{"headers_module", "mod_headers.so"},
+}
+
+// fastCGIModules are the modules sites running PHP through PHP-FPM need.
+var fastCGIModules = []apacheModule{
+	{"proxy_module", "mod_proxy.so"},
+	{"proxy_fcgi_module", "mod_proxy_fcgi.so"},
+}
+
+// EnableProxyModulesInHttpdConf makes sure httpd.conf loads the reverse proxy modules.
+func EnableProxyModulesInHttpdConf() error {
+	if utils.IsDryRun() {
+		utils.LogInfo("DRY RUN: Would enable the proxy modules in Apache configuration.")
+		return nil
+	}
+
+	utils.LogInfo("Enabling the proxy modules in Apache configuration...")
+
+	return enableModulesInHttpdConf(proxyModules)
+}
+
+// EnableFastCGIModulesInHttpdConf makes sure httpd.conf loads the modules
+// needed to hand PHP requests to PHP-FPM.
+func EnableFastCGIModulesInHttpdConf() error {
+	if utils.IsDryRun() {
+		utils.LogInfo("DRY RUN: Would enable the FastCGI proxy modules in Apache configuration.")
+		return nil
+	}
+
+	utils.LogInfo("Enabling the FastCGI proxy modules in Apache configuration...")
+
+	return enableModulesInHttpdConf(fastCGIModules)
+}
+
+// enableModulesInHttpdConf uncomments the LoadModule lines of the modules, adding
+// the missing ones after the last LoadModule line.
+func enableModulesInHttpdConf(modules []apacheModule) error {
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, module := range modules {
+		if conf.EnableModule(module.name, paths.Get().ModuleFile(module.file)) {
+			utils.LogSuccess(fmt.Sprintf("✔ Enabled %s in httpd.conf.", module.name))
+			changed = true
+		}
+	}
+
+	if !changed {
+		utils.LogSuccess("The required modules are already enabled in httpd.conf.")
+		return nil
+	}
+
+	// Write the updated content back to httpd.conf
+	if err := conf.Save(); err != nil {
+		return fmt.Errorf("failed to write to httpd.conf: %s", err.Error())
+	}
+
+	// New modules are only loaded by a full restart
+	RequestApacheRestart()
+	return nil
+}
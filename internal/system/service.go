@@ -0,0 +1,193 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// Service is a service the tool manages, named the way every service manager knows it.
+type Service struct {
+	Formula string   // Homebrew formula, e.g. "httpd"; launchd knows it as homebrew.mxcl.<formula>
+	Units   []string // systemd units, the first one that exists is used
+
+	DebianPackage string // apt package, e.g. "apache2"
+	FedoraPackage string // dnf package, e.g. "httpd"
+
+	// ReloadSignal makes the service reload its configuration gracefully where
+	// the manager can only send signals (launchd); empty when it cannot reload.
+	ReloadSignal string
+}
+
+// ApacheService is the Apache web server. SIGUSR1 is its graceful restart,
+// SIGHUP would drop the requests in flight.
+var ApacheService = Service{Formula: "httpd", Units: []string{"apache2", "httpd"}, DebianPackage: "apache2", FedoraPackage: "httpd", ReloadSignal: "SIGUSR1"}
+
+// MySQLService is the MySQL (or MariaDB) server.
+var MySQLService = Service{Formula: "mysql", Units: []string{"mysql", "mysqld", "mariadb"}, DebianPackage: "mysql-server", FedoraPackage: "mysql-server"}
+
+func (s Service) String() string {
+	if s.Formula != "" {
+		return s.Formula
+	}
+	return strings.Join(s.Units, "/")
+}
+
+// ServiceState is the state of a service.
+type ServiceState int
+
+const (
+	ServiceUnknown ServiceState = iota
+	ServiceStopped
+	ServiceRunning
+	ServiceFailed // The service stopped with an error
+)
+
+func (s ServiceState) String() string {
+	switch s {
+	case ServiceStopped:
+		return "stopped"
+	case ServiceRunning:
+		return "running"
+	case ServiceFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ServiceStatus is the state of a service as reported by its service manager.
+type ServiceStatus struct {
+	State  ServiceState
+	PID    int    // The main process, 0 when unknown or not running
+	Detail string // The state in the manager's own words, e.g. "started" or "active (running)"
+}
+
+// Running reports whether the service is running.
+func (s ServiceStatus) Running() bool {
+	return s.State == ServiceRunning
+}
+
+// ServiceManager starts and stops services.
+type ServiceManager interface {
+	Name() string
+	Start(s Service) error
+	Stop(s Service) error
+	Restart(s Service) error
+	Reload(s Service) error // Reloads the configuration, restarting when the manager cannot reload
+	Status(s Service) (ServiceStatus, error)
+
+	// StartCommand returns the command a user runs to start the service, for
+	// hints; empty when there is none.
+	StartCommand(s Service) string
+}
+
+var serviceManager ServiceManager
+
+// Services returns the service manager of this machine, detected on first use.
+func Services() (ServiceManager, error) {
+	if serviceManager != nil {
+		return serviceManager, nil
+	}
+
+	manager, err := detectServiceManager()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceManager = manager
+	return serviceManager, nil
+}
+
+// InstallCommand returns the command installing the service with the package
+// manager of this machine: Homebrew for a Homebrew layout, apt or dnf otherwise.
+func InstallCommand(s Service) string {
+	if paths.Get().Prefix == "" {
+		if _, err := exec.LookPath("apt-get"); err == nil {
+			return "apt install " + s.DebianPackage
+		}
+		if _, err := exec.LookPath("dnf"); err == nil {
+			return "dnf install " + s.FedoraPackage
+		}
+	}
+	return "brew install " + s.Formula
+}
+
+// StartCommand returns the command starting the service with the service
+// manager of this machine, empty when it cannot be detected.
+func StartCommand(s Service) string {
+	services, err := Services()
+	if err != nil {
+		return ""
+	}
+	return services.StartCommand(s)
+}
+
+// SetServiceManager replaces the detected service manager, e.g. with a FakeServiceManager.
+func SetServiceManager(manager ServiceManager) {
+	serviceManager = manager
+}
+
+// detectServiceManager picks the service manager named by the service_manager
+// setting or, when it is empty, Homebrew services for a Homebrew layout,
+// launchd on macOS and systemd on Linux.
+func detectServiceManager() (ServiceManager, error) {
+	switch name := paths.GetSettings().ServiceManager; name {
+	case "brew":
+		return brewServices{}, nil
+	case "launchctl":
+		return launchctl{}, nil
+	case "systemd":
+		return systemd{}, nil
+	case "fake":
+		return NewFakeServiceManager(), nil
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown service manager '%s' (expected brew, launchctl, systemd or fake)", name)
+	}
+
+	if _, err := exec.LookPath("brew"); err == nil && paths.Get().Prefix != "" {
+		return brewServices{}, nil
+	}
+	if runtime.GOOS == "darwin" {
+		return launchctl{}, nil
+	}
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		if _, err := os.Stat("/run/systemd/system"); err == nil {
+			return systemd{}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no supported service manager found (Homebrew services, launchd or systemd)")
+}
+
+// runServiceCommand runs a service manager command and returns its output. The
+// error carries the command and its output.
+func runServiceCommand(asOriginalUser bool, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	var err error
+	if asOriginalUser {
+		err = utils.RunAsOriginalUser(cmd)
+	} else {
+		err = cmd.Run()
+	}
+	if err != nil {
+		output := strings.TrimSpace(out.String())
+		if output == "" {
+			output = err.Error()
+		}
+		return out.String(), fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), output)
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,81 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// brewServices manages services with 'brew services', as the user who installed them.
+type brewServices struct{}
+
+func (brewServices) Name() string { return "brew" }
+
+func (b brewServices) Start(s Service) error {
+	_, err := runServiceCommand(true, "brew", "services", "start", s.Formula)
+	return err
+}
+
+func (b brewServices) Stop(s Service) error {
+	_, err := runServiceCommand(true, "brew", "services", "stop", s.Formula)
+	return err
+}
+
+func (b brewServices) Restart(s Service) error {
+	_, err := runServiceCommand(true, "brew", "services", "restart", s.Formula)
+	return err
+}
+
+func (b brewServices) StartCommand(s Service) string {
+	return "brew services start " + s.Formula
+}
+
+// Reload signals the launchd job of the service, since 'brew services' has no
+// reload, and restarts the services without a reload signal.
+func (b brewServices) Reload(s Service) error {
+	if s.ReloadSignal == "" {
+		return b.Restart(s)
+	}
+	return launchctl{}.Reload(s)
+}
+
+// brewServiceInfo is an entry of 'brew services info --json'.
+type brewServiceInfo struct {
+	Name     string `json:"name"`
+	Running  bool   `json:"running"`
+	PID      int    `json:"pid"`
+	ExitCode int    `json:"exit_code"`
+	Status   string `json:"status"` // "started", "stopped", "none", "error"...
+}
+
+func (b brewServices) Status(s Service) (ServiceStatus, error) {
+	out, err := runServiceCommand(true, "brew", "services", "info", "--json", s.Formula)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	return parseBrewServicesInfo(s, out)
+}
+
+// parseBrewServicesInfo reads the status of the service from the output of
+// 'brew services info --json'.
+func parseBrewServicesInfo(s Service, out string) (ServiceStatus, error) {
+	var infos []brewServiceInfo
+	if err := json.Unmarshal([]byte(out), &infos); err != nil {
+		return ServiceStatus{}, fmt.Errorf("failed to parse the status of %s: %s", s, err.Error())
+	}
+	if len(infos) == 0 {
+		return ServiceStatus{}, fmt.Errorf("brew services knows nothing about %s", s)
+	}
+
+	info := infos[0]
+	status := ServiceStatus{PID: info.PID, Detail: info.Status}
+	switch {
+	case info.Running:
+		status.State = ServiceRunning
+	case info.Status == "error" || info.ExitCode != 0:
+		status.State = ServiceFailed
+	default:
+		status.State = ServiceStopped
+	}
+	return status, nil
+}
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"fmt"
+)
+
+// FakeServiceManager keeps the state of the services in memory and records the
+// calls made to it. Tests use it in place of the real service manager, and the
+// "fake" service_manager setting runs the commands without touching any service.
+type FakeServiceManager struct {
+	States map[string]ServiceState // By service name, see Service.String
+	Calls  []string                // The actions in order, e.g. "restart httpd"
+	Err    error                   // Returned by every call when set
+}
+
+// NewFakeServiceManager returns a fake service manager where every service is stopped.
+func NewFakeServiceManager() *FakeServiceManager {
+	return &FakeServiceManager{States: map[string]ServiceState{}}
+}
+
+func (f *FakeServiceManager) Name() string { return "fake" }
+
+func (f *FakeServiceManager) set(action string, s Service, state ServiceState) error {
+	f.Calls = append(f.Calls, fmt.Sprintf("%s %s", action, s))
+	if f.Err != nil {
+		return f.Err
+	}
+
+	f.States[s.String()] = state
+	return nil
+}
+
+func (f *FakeServiceManager) Start(s Service) error {
+	return f.set("start", s, ServiceRunning)
+}
+
+func (f *FakeServiceManager) Stop(s Service) error {
+	return f.set("stop", s, ServiceStopped)
+}
+
+func (f *FakeServiceManager) Restart(s Service) error {
+	return f.set("restart", s, ServiceRunning)
+}
+
+// Reload fails like systemctl does when the service is not running.
+func (f *FakeServiceManager) Reload(s Service) error {
+	if f.States[s.String()] != ServiceRunning && f.Err == nil {
+		f.Calls = append(f.Calls, fmt.Sprintf("reload %s", s))
+		return fmt.Errorf("%s is not running", s)
+	}
+	return f.set("reload", s, ServiceRunning)
+}
+
+// StartCommand returns nothing: no command starts a fake service.
+func (f *FakeServiceManager) StartCommand(s Service) string {
+	return ""
+}
+
+func (f *FakeServiceManager) Status(s Service) (ServiceStatus, error) {
+	f.Calls = append(f.Calls, fmt.Sprintf("status %s", s))
+	if f.Err != nil {
+		return ServiceStatus{}, f.Err
+	}
+
+	state, found := f.States[s.String()]
+	if !found {
+		state = ServiceStopped
+	}
+	return ServiceStatus{State: state, Detail: state.String()}, nil
+}
@@ -0,0 +1,172 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// launchctl manages the launchd jobs of Homebrew services directly, for the
+// machines where 'brew services' is not available (e.g. a locked down brew).
+type launchctl struct{}
+
+func (launchctl) Name() string { return "launchctl" }
+
+// job returns the launchd label, the domain and the plist of the service. Jobs
+// started with 'sudo brew services' live in the system domain, the others in
+// the GUI domain of the user who installed them.
+func (launchctl) job(s Service) (label, domain, plist string, err error) {
+	if s.Formula == "" {
+		return "", "", "", fmt.Errorf("%s is not a Homebrew service", s)
+	}
+	label = "homebrew.mxcl." + s.Formula
+
+	plist = filepath.Join("/Library/LaunchDaemons", label+".plist")
+	if _, err := os.Stat(plist); err == nil {
+		return label, "system", plist, nil
+	}
+
+	account, err := user.Lookup(utils.GetOriginalUser())
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to look up the user running %s: %s", s, err.Error())
+	}
+	return label, "gui/" + account.Uid, filepath.Join(utils.GetOriginalHome(), "Library/LaunchAgents", label+".plist"), nil
+}
+
+// loaded reports whether the job is known to launchd.
+func (l launchctl) loaded(domain, label string) bool {
+	_, err := runServiceCommand(false, "launchctl", "print", domain+"/"+label)
+	return err == nil
+}
+
+func (l launchctl) Start(s Service) error {
+	label, domain, plist, err := l.job(s)
+	if err != nil {
+		return err
+	}
+
+	if l.loaded(domain, label) {
+		_, err = runServiceCommand(false, "launchctl", "kickstart", domain+"/"+label)
+		return err
+	}
+
+	if _, err := os.Stat(plist); err != nil {
+		return fmt.Errorf("no launchd job for %s: %s is missing (run 'brew services start %s' once)", s, plist, s.Formula)
+	}
+	_, err = runServiceCommand(false, "launchctl", "bootstrap", domain, plist)
+	return err
+}
+
+func (l launchctl) Stop(s Service) error {
+	label, domain, _, err := l.job(s)
+	if err != nil {
+		return err
+	}
+
+	if !l.loaded(domain, label) {
+		return nil
+	}
+	_, err = runServiceCommand(false, "launchctl", "bootout", domain+"/"+label)
+	return err
+}
+
+func (l launchctl) Restart(s Service) error {
+	label, domain, _, err := l.job(s)
+	if err != nil {
+		return err
+	}
+
+	if !l.loaded(domain, label) {
+		return l.Start(s)
+	}
+	_, err = runServiceCommand(false, "launchctl", "kickstart", "-k", domain+"/"+label)
+	return err
+}
+
+// Reload sends the graceful reload signal of the service, e.g. SIGUSR1 for
+// Apache, and restarts the services without one.
+func (l launchctl) Reload(s Service) error {
+	if s.ReloadSignal == "" {
+		return l.Restart(s)
+	}
+
+	label, domain, _, err := l.job(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = runServiceCommand(false, "launchctl", "kill", s.ReloadSignal, domain+"/"+label)
+	return err
+}
+
+func (l launchctl) StartCommand(s Service) string {
+	label, domain, _, err := l.job(s)
+	if err != nil {
+		return ""
+	}
+	return "launchctl kickstart " + domain + "/" + label
+}
+
+func (l launchctl) Status(s Service) (ServiceStatus, error) {
+	label, domain, _, err := l.job(s)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	// launchctl print fails for the jobs that are not loaded
+	out, err := runServiceCommand(false, "launchctl", "print", domain+"/"+label)
+	if err != nil {
+		return ServiceStatus{State: ServiceStopped, Detail: "not loaded"}, nil
+	}
+
+	return parseLaunchctlPrint(out), nil
+}
+
+// parseLaunchctlPrint reads the state, pid and last exit code of a job from the
+// output of 'launchctl print', made of "key = value" lines. Only the keys of the
+// job itself count, not those of its nested "key = {" blocks (endpoints...).
+func parseLaunchctlPrint(out string) ServiceStatus {
+	var status ServiceStatus
+	lastExit := ""
+	depth := 0
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "}" {
+			depth--
+			continue
+		}
+		if strings.HasSuffix(line, "{") {
+			depth++
+			continue
+		}
+
+		key, value, found := strings.Cut(line, " = ")
+		if !found || depth > 1 {
+			continue
+		}
+
+		switch key {
+		case "state":
+			status.Detail = value
+		case "pid":
+			status.PID, _ = strconv.Atoi(value)
+		case "last exit code":
+			lastExit = value
+		}
+	}
+
+	switch {
+	case status.Detail == "running":
+		status.State = ServiceRunning
+	case lastExit != "" && lastExit != "0" && lastExit != "(never exited)":
+		status.State = ServiceFailed
+	default:
+		status.State = ServiceStopped
+	}
+	return status
+}
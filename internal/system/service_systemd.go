@@ -0,0 +1,86 @@
+package system
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// systemd manages services with systemctl, as distribution packages install them.
+type systemd struct{}
+
+func (systemd) Name() string { return "systemd" }
+
+// unit returns the first unit of the service systemd knows, since each
+// distribution names them differently (apache2 or httpd, mysql or mariadb...).
+func (systemd) unit(s Service) (string, error) {
+	for _, unit := range s.Units {
+		out, err := runServiceCommand(false, "systemctl", "show", unit, "--property=LoadState", "--value")
+		if err == nil && strings.TrimSpace(out) == "loaded" {
+			return unit, nil
+		}
+	}
+	return "", fmt.Errorf("none of the systemd units %s is installed", strings.Join(s.Units, ", "))
+}
+
+func (d systemd) run(verb string, s Service) error {
+	unit, err := d.unit(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = runServiceCommand(false, "systemctl", verb, unit)
+	return err
+}
+
+func (d systemd) Start(s Service) error   { return d.run("start", s) }
+func (d systemd) Stop(s Service) error    { return d.run("stop", s) }
+func (d systemd) Restart(s Service) error { return d.run("restart", s) }
+func (d systemd) Reload(s Service) error  { return d.run("reload", s) }
+
+func (d systemd) StartCommand(s Service) string {
+	unit, err := d.unit(s)
+	if err != nil {
+		unit = s.Units[0]
+	}
+	return "systemctl start " + unit
+}
+
+func (d systemd) Status(s Service) (ServiceStatus, error) {
+	unit, err := d.unit(s)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	out, err := runServiceCommand(false, "systemctl", "show", unit, "--property=ActiveState,SubState,MainPID")
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	return parseSystemctlShow(out), nil
+}
+
+// parseSystemctlShow reads the output of 'systemctl show', made of "Key=value" lines.
+func parseSystemctlShow(out string) ServiceStatus {
+	properties := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if key, value, found := strings.Cut(strings.TrimSpace(line), "="); found {
+			properties[key] = value
+		}
+	}
+
+	status := ServiceStatus{Detail: fmt.Sprintf("%s (%s)", properties["ActiveState"], properties["SubState"])}
+	status.PID, _ = strconv.Atoi(properties["MainPID"])
+
+	switch properties["ActiveState"] {
+	case "active", "reloading":
+		status.State = ServiceRunning
+	case "failed":
+		status.State = ServiceFailed
+	case "inactive", "deactivating":
+		status.State = ServiceStopped
+	default:
+		status.State = ServiceUnknown
+	}
+	return status
+}
@@ -0,0 +1,239 @@
+package system
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/paths"
+)
+
+func TestParseSystemctlShow(t *testing.T) {
+	for _, tc := range []struct {
+		out  string
+		want ServiceStatus
+	}{
+		{"ActiveState=active\nSubState=running\nMainPID=4242\n", ServiceStatus{State: ServiceRunning, PID: 4242, Detail: "active (running)"}},
+		{"ActiveState=reloading\nSubState=reload\nMainPID=4242\n", ServiceStatus{State: ServiceRunning, PID: 4242, Detail: "reloading (reload)"}},
+		{"ActiveState=inactive\nSubState=dead\nMainPID=0\n", ServiceStatus{State: ServiceStopped, Detail: "inactive (dead)"}},
+		{"ActiveState=failed\nSubState=failed\nMainPID=0\n", ServiceStatus{State: ServiceFailed, Detail: "failed (failed)"}},
+		{"MainPID=0\nActiveState=activating\nSubState=start\n", ServiceStatus{State: ServiceUnknown, Detail: "activating (start)"}},
+	} {
+		if got := parseSystemctlShow(tc.out); got != tc.want {
+			t.Errorf("parseSystemctlShow(%q) = %+v, want %+v", tc.out, got, tc.want)
+		}
+	}
+}
+
+// launchctlPrint returns the output of 'launchctl print' for a job, with a
+// nested block whose keys must not be mistaken for the job's.
+func launchctlPrint(state, pid, lastExit string) string {
+	lines := []string{
+		"gui/501/homebrew.mxcl.httpd = {",
+		"\tactive count = 1",
+		"\tpath = /Users/dev/Library/LaunchAgents/homebrew.mxcl.httpd.plist",
+		"\tstate = " + state,
+		"",
+		"\tprogram = /opt/homebrew/opt/httpd/bin/httpd",
+	}
+	if pid != "" {
+		lines = append(lines, "\tpid = "+pid)
+	}
+	lines = append(lines,
+		"\tlast exit code = "+lastExit,
+		"",
+		"\tspawn type = daemon (3)",
+		"\tendpoints = {",
+		"\t\tstate = active",
+		"\t\tpid = 1",
+		"\t}",
+		"}",
+	)
+	return strings.Join(lines, "\n")
+}
+
+func TestParseLaunchctlPrint(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		out  string
+		want ServiceStatus
+	}{
+		{"running", launchctlPrint("running", "812", "(never exited)"), ServiceStatus{State: ServiceRunning, PID: 812, Detail: "running"}},
+		{"stopped cleanly", launchctlPrint("not running", "", "0"), ServiceStatus{State: ServiceStopped, Detail: "not running"}},
+		{"never started", launchctlPrint("not running", "", "(never exited)"), ServiceStatus{State: ServiceStopped, Detail: "not running"}},
+		{"crashed", launchctlPrint("not running", "", "1"), ServiceStatus{State: ServiceFailed, Detail: "not running"}},
+		{"empty", "", ServiceStatus{State: ServiceStopped}},
+	} {
+		if got := parseLaunchctlPrint(tc.out); got != tc.want {
+			t.Errorf("%s: parseLaunchctlPrint = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseBrewServicesInfo(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		out  string
+		want ServiceStatus
+	}{
+		{"started", `[{"name":"httpd","service_name":"homebrew.mxcl.httpd","running":true,"loaded":true,"pid":812,"exit_code":0,"status":"started"}]`, ServiceStatus{State: ServiceRunning, PID: 812, Detail: "started"}},
+		{"stopped", `[{"name":"httpd","running":false,"loaded":false,"pid":null,"exit_code":null,"status":"none"}]`, ServiceStatus{State: ServiceStopped, Detail: "none"}},
+		{"error", `[{"name":"httpd","running":false,"loaded":true,"pid":null,"exit_code":1,"status":"error"}]`, ServiceStatus{State: ServiceFailed, Detail: "error"}},
+	} {
+		got, err := parseBrewServicesInfo(ApacheService, tc.out)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: parseBrewServicesInfo = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	for name, out := range map[string]string{"no entry": "[]", "not JSON": "Error: unknown command"} {
+		if _, err := parseBrewServicesInfo(ApacheService, out); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDetectServiceManagerOverrides(t *testing.T) {
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	for setting, want := range map[string]string{"brew": "brew", "launchctl": "launchctl", "systemd": "systemd", "fake": "fake"} {
+		t.Setenv("LOCALHOST_SERVICE_MANAGER", setting)
+		if err := paths.Init(t.TempDir()); err != nil {
+			t.Fatalf("paths.Init: %v", err)
+		}
+
+		manager, err := detectServiceManager()
+		if err != nil {
+			t.Errorf("%s: %v", setting, err)
+			continue
+		}
+		if manager.Name() != want {
+			t.Errorf("%s: detected %s", setting, manager.Name())
+		}
+	}
+
+	// Rejected at startup, before any service is touched
+	t.Setenv("LOCALHOST_SERVICE_MANAGER", "upstart")
+	if err := paths.Init(t.TempDir()); err == nil {
+		t.Errorf("upstart: expected an error")
+	}
+}
+
+func TestRestartApacheThroughServiceManager(t *testing.T) {
+	fake := NewFakeServiceManager()
+	SetServiceManager(fake)
+	t.Cleanup(func() { SetServiceManager(nil) })
+
+	if err := restartApache(); err != nil {
+		t.Fatalf("restartApache: %v", err)
+	}
+	if len(fake.Calls) != 1 || fake.Calls[0] != "restart httpd" {
+		t.Errorf("calls = %v, want [restart httpd]", fake.Calls)
+	}
+	if status, _ := fake.Status(ApacheService); !status.Running() {
+		t.Errorf("Apache is %s after a restart", status.State)
+	}
+
+	fake.Err = errors.New("launchd refused")
+	if err := restartApache(); err == nil || !strings.Contains(err.Error(), "launchd refused") {
+		t.Errorf("restartApache = %v, want the service manager's error", err)
+	}
+}
+
+func TestReloadApacheThroughServiceManager(t *testing.T) {
+	// No apachectl: the configuration test is skipped
+	root := t.TempDir()
+	t.Setenv("PATH", "")
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	t.Setenv("LOCALHOST_PREFIX", filepath.Join(root, "homebrew"))
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := NewFakeServiceManager()
+	SetServiceManager(fake)
+	t.Cleanup(func() { SetServiceManager(nil) })
+
+	// A stopped Apache cannot reload and is restarted instead
+	if err := ReloadApache(); err != nil {
+		t.Fatalf("ReloadApache: %v", err)
+	}
+	if strings.Join(fake.Calls, ",") != "reload httpd,restart httpd" {
+		t.Errorf("calls = %v, want [reload httpd restart httpd]", fake.Calls)
+	}
+
+	fake.Calls = nil
+	if err := ReloadApache(); err != nil {
+		t.Fatalf("ReloadApache: %v", err)
+	}
+	if strings.Join(fake.Calls, ",") != "reload httpd" {
+		t.Errorf("calls = %v, want [reload httpd]", fake.Calls)
+	}
+}
+
+func TestFakeServiceManagerReload(t *testing.T) {
+	fake := NewFakeServiceManager()
+
+	// Like systemctl, reloading a stopped service fails
+	if err := fake.Reload(MySQLService); err == nil {
+		t.Errorf("Reload of a stopped service: expected an error")
+	}
+	if err := fake.Start(MySQLService); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Reload(MySQLService); err != nil {
+		t.Errorf("Reload of a running service: %v", err)
+	}
+
+	want := []string{"reload mysql", "start mysql", "reload mysql"}
+	if strings.Join(fake.Calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", fake.Calls, want)
+	}
+}
+
+func TestServiceHintCommands(t *testing.T) {
+	// No systemctl: the first unit is named
+	t.Setenv("PATH", "")
+	for _, tc := range []struct {
+		manager ServiceManager
+		service Service
+		want    string
+	}{
+		{brewServices{}, ApacheService, "brew services start httpd"},
+		{systemd{}, ApacheService, "systemctl start apache2"},
+		{systemd{}, MySQLService, "systemctl start mysql"},
+		{NewFakeServiceManager(), ApacheService, ""},
+	} {
+		if got := tc.manager.StartCommand(tc.service); got != tc.want {
+			t.Errorf("%s: StartCommand(%s) = %q, want %q", tc.manager.Name(), tc.service, got, tc.want)
+		}
+	}
+
+	root := t.TempDir()
+	t.Setenv("LOCALHOST_CONFIG", filepath.Join(root, "missing"))
+	t.Setenv("LOCALHOST_PREFIX", "/opt/homebrew")
+	if err := paths.Init(root); err != nil {
+		t.Fatal(err)
+	}
+	if got := InstallCommand(MySQLService); got != "brew install mysql" {
+		t.Errorf("Homebrew: InstallCommand = %q", got)
+	}
+
+	// A distribution layout with apt
+	t.Setenv("LOCALHOST_PREFIX", "")
+	debianRoot(t)
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "apt-get"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	if got := InstallCommand(ApacheService); got != "apt install apache2" {
+		t.Errorf("Debian: InstallCommand = %q", got)
+	}
+}
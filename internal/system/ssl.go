@@ -1,55 +1,54 @@
 package system
 
 import (
-	"bufio"
+	"crypto/x509"
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
+	"time"
 
+	"github.com/liviu-hariton/localhost/internal/apacheconf"
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
-// Default SSL certificate paths
-const sslCertificateFile = "/opt/homebrew/etc/httpd/ssl/server.crt"
-const sslCertificateKeyFile = "/opt/homebrew/etc/httpd/ssl/server.key"
+// CheckSSLCertificate loads the certificate the virtual hosts use and verifies that
+// it is currently valid.
+func CheckSSLCertificate() (*x509.Certificate, error) {
+	cert, err := certs.LoadCertificate(paths.Get().SSLCertificateFile())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSL certificate: %s", err.Error())
+	}
 
-// HttpdSSLConfPath defines the path to the Apache SSL configuration file.
-const HttpdSSLConfPath = "/opt/homebrew/etc/httpd/extra/httpd-ssl.conf"
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return cert, fmt.Errorf("SSL certificate is not valid before %s", cert.NotBefore.Format("2006-01-02"))
+	}
+	if now.After(cert.NotAfter) {
+		return cert, fmt.Errorf("SSL certificate expired on %s", cert.NotAfter.Format("2006-01-02"))
+	}
 
-// EnsureSSLCertificates ensures the SSL certificate and key files exist, generating them if necessary.
+	return cert, nil
+}
+
+// EnsureSSLCertificates ensures the certificate and key files of the default SSL
+// virtual host exist, issuing them from the local certificate authority if necessary.
 func EnsureSSLCertificates() error {
 	utils.LogInfo("Checking for SSL certificates...")
 
 	// Check if the certificate and key files exist
-	if _, err := os.Stat(sslCertificateFile); os.IsNotExist(err) {
-		utils.LogWarning(fmt.Sprintf("SSL certificate not found at %s. Generating a self-signed certificate...\n", sslCertificateFile))
+	if _, err := os.Stat(paths.Get().SSLCertificateFile()); os.IsNotExist(err) {
+		utils.LogWarning(fmt.Sprintf("SSL certificate not found at %s. Issuing one from the local certificate authority...\n", paths.Get().SSLCertificateFile()))
 
-		// Ensure the /opt/homebrew/etc/httpd/ssl directory exists
+		// Issue the certificate of the default SSL virtual host
 		if !utils.IsDryRun() {
-			if err := utils.CreateDirectory("/opt/homebrew/etc/httpd/ssl"); err != nil {
-				return utils.LogError("Creating SSL directory", err)
-			}
-		} else {
-			utils.LogInfo("DRY RUN: Would create the SSL directory.")
-		}
-
-		// Generate a self-signed certificate
-		if !utils.IsDryRun() {
-			cmd := exec.Command("openssl", "req", "-x509", "-nodes", "-days", "365", "-newkey", "rsa:2048",
-				"-keyout", sslCertificateKeyFile,
-				"-out", sslCertificateFile,
-				"-subj", "/C=US/ST=State/L=City/O=Organization/OU=Unit/CN=localhost")
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			if err := cmd.Run(); err != nil {
-				return utils.LogError("Generating self-signed SSL certificate", err)
+			if err := IssueCertificate(paths.Get().SSLCertificateFile(), paths.Get().SSLCertificateKeyFile(), []string{"localhost", "127.0.0.1", "::1"}); err != nil {
+				return utils.LogError("Issuing the SSL certificate", err)
 			}
 
-			utils.LogSuccess(fmt.Sprintf("✔ Self-signed SSL certificate generated:\n - Certificate: %s\n - Key: %s\n", sslCertificateFile, sslCertificateKeyFile))
+			utils.LogSuccess(fmt.Sprintf("✔ SSL certificate issued:\n - Certificate: %s\n - Key: %s\n", paths.Get().SSLCertificateFile(), paths.Get().SSLCertificateKeyFile()))
 		} else {
-			utils.LogInfo("DRY RUN: Would Generate a self-signed certificate.")
+			utils.LogInfo("DRY RUN: Would issue the SSL certificate from the local certificate authority.")
 		}
 
 		// Enable ssl_module in httpd.conf
@@ -60,14 +59,11 @@ func EnsureSSLCertificates() error {
 		} else {
 			utils.LogInfo("DRY RUN: Would enable SSL module in httpd.conf.")
 		}
-
-		// Restart Apache to apply changes
-		if restartErr := RestartApache(); restartErr != nil {
-			return fmt.Errorf("failed to restart Apache after enabling SSL module: %s", restartErr.Error())
-		}
-
 	} else {
 		utils.LogSuccess("SSL certificates already exist.")
+		if cert, err := certs.LoadCertificate(paths.Get().SSLCertificateFile()); err == nil {
+			warnIfExpiring(Certificate{Cert: cert})
+		}
 	}
 
 	return nil
@@ -81,139 +77,71 @@ func EnableSSLModuleInHttpdConf() error {
 
 	utils.LogInfo("Enabling SSL module in Apache configuration...")
 
-	file, err := os.Open(HttpdConfPath)
+	conf, err := apacheconf.LoadResolved(paths.Get().HttpdConf, paths.Get().Root)
 	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf: %s", err.Error())
-	}
-	defer file.Close()
-
-	var lines []string
-	sslModuleLoaded := false
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		lines = append(lines, line)
-
-		// Check if the SSL module is already loaded
-		if strings.Contains(line, "LoadModule ssl_module") && !strings.HasPrefix(line, "#") {
-			sslModuleLoaded = true
-		} else if strings.Contains(line, "LoadModule ssl_module") && strings.HasPrefix(line, "#") {
-			// Uncomment the SSL module loading line if it's commented
-			line = strings.TrimPrefix(line, "#")
-			sslModuleLoaded = true
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read httpd.conf: %s", err.Error())
-	}
-
-	// Add the SSL module loading line if not found
-	if !sslModuleLoaded {
-		lines = append([]string{"LoadModule ssl_module lib/httpd/modules/mod_ssl.so"}, lines...)
-		utils.LogSuccess("✔ Added SSL module loading line to httpd.conf.")
-	}
-
-	// Write the updated content back to httpd.conf
-	file, err = os.OpenFile(HttpdConfPath, os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf for writing: %s", err.Error())
-	}
-	defer file.Close()
-
-	for _, line := range lines {
-		if _, err := file.WriteString(line + "\n"); err != nil {
-			return fmt.Errorf("failed to write to httpd.conf: %s", err.Error())
+		return err
+	}
+	changed := false
+
+	// httpd-ssl.conf keeps the SSL session cache in shared memory
+	for _, module := range []apacheModule{
+		{"ssl_module", "mod_ssl.so"},
+		{"socache_shmcb_module", "mod_socache_shmcb.so"},
+	} {
+		if conf.EnableModule(module.name, paths.Get().ModuleFile(module.file)) {
+			changed = true
+			RequestApacheRestart()
+			utils.LogSuccess(fmt.Sprintf("✔ Enabled %s in httpd.conf.", module.name))
 		}
 	}
 
-	// uncomment the Include /opt/homebrew/etc/httpd/extra/httpd-ssl.conf line if it's commented
-	includeSslConfAdded := false
-	for _, line := range lines {
-		if strings.Contains(line, "Include "+HttpdSSLConfPath) {
-			includeSslConfAdded = true
-			break
+	// Include httpd-ssl.conf, uncommenting the line if it's commented
+	sslConfPath := paths.Get().HttpdSSLConf
+	if sslConfPath != "" {
+		if conf.AddInclude(sslConfPath) {
+			changed = true
+			utils.LogSuccess("✔ Added Include " + sslConfPath + " line to httpd.conf.")
+		} else {
+			utils.LogSuccess("Include " + sslConfPath + " line already exists in httpd.conf.")
 		}
 	}
-	if !includeSslConfAdded {
-		lines = append(lines, "Include "+HttpdSSLConfPath)
-		utils.LogSuccess("✔ Added Include " + HttpdSSLConfPath + " line to httpd.conf.")
-	} else {
-		utils.LogSuccess("Include " + HttpdSSLConfPath + " line already exists in httpd.conf.")
-	}
 
 	// Write the updated content back to httpd.conf
-	file, err = os.OpenFile(HttpdConfPath, os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf for writing: %s", err.Error())
-	}
-	defer file.Close()
-
-	for _, line := range lines {
-		if _, err := file.WriteString(line + "\n"); err != nil {
+	if changed {
+		if err := conf.Save(); err != nil {
 			return fmt.Errorf("failed to write to httpd.conf: %s", err.Error())
 		}
+		RequestApacheReload()
 	}
 
-	// read the httpd-ssl.conf file
-	httpdSslConfFile, err := os.Open(HttpdSSLConfPath)
-	if err != nil {
-		return fmt.Errorf("failed to open httpd-ssl.conf: %s", err.Error())
-	}
-	defer httpdSslConfFile.Close()
-
-	var httpdSslConfLines []string
-	scanner = bufio.NewScanner(httpdSslConfFile)
-	for scanner.Scan() {
-		line := scanner.Text()
-		httpdSslConfLines = append(httpdSslConfLines, line)
+	if sslConfPath == "" {
+		utils.LogSuccess("SSL module enabled in httpd.conf.")
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read httpd-ssl.conf: %s", err.Error())
+	// Point the default SSL virtual host of httpd-ssl.conf at the SSL certificate and key files
+	sslConf, err := apacheconf.Load(sslConfPath)
+	if err != nil {
+		return err
 	}
 
-	// add SSLCertificateFile and SSLCertificateKeyFile lines pointing to the SSL certificate and key files
-	sslCertificateFileAdded := false
-	for _, line := range lines {
-		if strings.Contains(line, "SSLCertificateFile "+sslCertificateFile) {
-			sslCertificateFileAdded = true
-			break
-		}
-	}
-	if !sslCertificateFileAdded {
-		lines = append(lines, "SSLCertificateFile "+sslCertificateFile)
-		utils.LogSuccess("✔ Added SSLCertificateFile line to httpd.conf.")
-	} else {
-		utils.LogSuccess("SSLCertificateFile line already exists in httpd.conf.")
+	vhost := sslConf.FindSection("VirtualHost")
+	if vhost == nil {
+		utils.LogWarning(fmt.Sprintf("No <VirtualHost> section in %s, leaving its certificate settings alone.", sslConfPath))
+		utils.LogSuccess("SSL module enabled in httpd.conf.")
+		return nil
 	}
 
-	sslCertificateKeyFileAdded := false
-	for _, line := range lines {
-		if strings.Contains(line, "SSLCertificateKeyFile "+sslCertificateKeyFile) {
-			sslCertificateKeyFileAdded = true
-			break
+	certChanged := sslConf.SetDirective(vhost, "SSLCertificateFile", paths.Get().SSLCertificateFile())
+	keyChanged := sslConf.SetDirective(vhost, "SSLCertificateKeyFile", paths.Get().SSLCertificateKeyFile())
+	if certChanged || keyChanged {
+		if err := sslConf.Save(); err != nil {
+			return fmt.Errorf("failed to write to %s: %s", sslConfPath, err.Error())
 		}
-	}
-	if !sslCertificateKeyFileAdded {
-		lines = append(lines, "SSLCertificateKeyFile "+sslCertificateKeyFile)
-		utils.LogSuccess("✔ Added SSLCertificateKeyFile line to httpd.conf.")
+		RequestApacheReload()
+		utils.LogSuccess(fmt.Sprintf("✔ Pointed %s at the SSL certificate.", sslConfPath))
 	} else {
-		utils.LogSuccess("SSLCertificateKeyFile line already exists in httpd.conf.")
-	}
-
-	// Write the updated content back to httpd.conf
-	file, err = os.OpenFile(HttpdConfPath, os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open httpd.conf for writing: %s", err.Error())
-	}
-	defer file.Close()
-
-	for _, line := range lines {
-		if _, err := file.WriteString(line + "\n"); err != nil {
-			return fmt.Errorf("failed to write to httpd.conf: %s", err.Error())
-		}
+		utils.LogSuccess(fmt.Sprintf("%s already uses the SSL certificate.", sslConfPath))
 	}
 
 	utils.LogSuccess("SSL module enabled in httpd.conf.")
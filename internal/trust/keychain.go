@@ -0,0 +1,60 @@
+package trust
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// systemKeychain is the keychain holding the roots trusted by every user of the Mac.
+const systemKeychain = "/Library/Keychains/System.keychain"
+
+// keychain is the macOS System keychain, used by Safari, Chrome and curl.
+type keychain struct {
+	root string
+}
+
+func (keychain) Name() string { return "macOS System keychain" }
+
+// Available reports whether this is a Mac; the keychain cannot live under another root.
+func (k keychain) Available() bool {
+	if runtime.GOOS != "darwin" || k.root != "/" {
+		return false
+	}
+	_, err := exec.LookPath("security")
+	return err == nil
+}
+
+func (k keychain) Install(ca CA) error {
+	_, err := run(false, "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", systemKeychain, ca.File)
+	return err
+}
+
+// fingerprint returns the SHA-1 fingerprint the keychain identifies the certificate by.
+func fingerprint(ca CA) string {
+	return strings.ToUpper(fmt.Sprintf("%x", sha1.Sum(ca.Cert.Raw)))
+}
+
+// Installed reports whether the keychain holds the certificate, found by its
+// SHA-1 fingerprint.
+func (k keychain) Installed(ca CA) bool {
+	out, err := run(false, "security", "find-certificate", "-a", "-Z", "-c", ca.Cert.Subject.CommonName, systemKeychain)
+	return err == nil && strings.Contains(out, fingerprint(ca))
+}
+
+// Uninstall drops the trust settings of the certificate, then the certificate
+// itself. A certificate that is not in the keychain is not an error.
+func (k keychain) Uninstall(ca CA) error {
+	if !k.Installed(ca) {
+		return nil
+	}
+
+	// The trust settings outlive the certificate otherwise
+	if _, err := run(false, "security", "remove-trusted-cert", "-d", ca.File); err != nil {
+		return err
+	}
+	_, err := run(false, "security", "delete-certificate", "-Z", fingerprint(ca), systemKeychain)
+	return err
+}
@@ -0,0 +1,84 @@
+package trust
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// linuxDir is a directory of trust anchors and the command rebuilding the
+// system bundle from it.
+type linuxDir struct {
+	name    string
+	dir     string // Relative to the root
+	ext     string
+	refresh []string
+}
+
+var linuxDirs = []linuxDir{
+	{"Debian/Ubuntu system store", "usr/local/share/ca-certificates", ".crt", []string{"update-ca-certificates"}},
+	{"Fedora/RHEL system store", "etc/pki/ca-trust/source/anchors", ".pem", []string{"update-ca-trust", "extract"}},
+	{"Arch Linux system store", "etc/ca-certificates/trust-source/anchors", ".crt", []string{"trust", "extract-compat"}},
+	{"openSUSE system store", "etc/pki/trust/anchors", ".pem", []string{"update-ca-certificates"}},
+}
+
+// dirStore is a Linux trust anchor directory. Under another root than "/" the
+// files are written but the system bundle is not rebuilt, which makes the store
+// testable in a temporary directory.
+type dirStore struct {
+	linuxDir
+	root string
+}
+
+func (d dirStore) Name() string { return d.name }
+
+// Available reports whether the anchor directory exists: each distribution ships its own.
+func (d dirStore) Available() bool {
+	info, err := os.Stat(filepath.Join(d.root, d.dir))
+	return err == nil && info.IsDir()
+}
+
+// Path returns the file the certificate is stored in.
+func (d dirStore) Path(ca CA) string {
+	return filepath.Join(d.root, d.dir, ca.Name+d.ext)
+}
+
+// Installed reports whether the anchor file holds the certificate.
+func (d dirStore) Installed(ca CA) bool {
+	data, err := os.ReadFile(d.Path(ca))
+	return err == nil && bytes.Equal(data, ca.PEM())
+}
+
+func (d dirStore) Install(ca CA) error {
+	if err := utils.WriteFileAtomic(d.Path(ca), ca.PEM(), 0644); err != nil {
+		return err
+	}
+	return d.rebuild()
+}
+
+func (d dirStore) Uninstall(ca CA) error {
+	if err := os.Remove(d.Path(ca)); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove %s: %s", d.Path(ca), err.Error())
+	}
+	return d.rebuild()
+}
+
+// rebuild regenerates the system bundle from the anchor directories.
+func (d dirStore) rebuild() error {
+	if d.root != "/" {
+		return nil
+	}
+	if _, err := exec.LookPath(d.refresh[0]); err != nil {
+		return fmt.Errorf("%s is missing: run it once installed to rebuild the system store", d.refresh[0])
+	}
+
+	_, err := run(false, d.refresh[0], d.refresh[1:]...)
+	return err
+}
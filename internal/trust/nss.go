@@ -0,0 +1,92 @@
+package trust
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// nssProfiles are the globs, relative to the home directory, of the NSS
+// databases of Firefox and Chromium, which ignore the system store on Linux
+// (and, for Firefox, on macOS).
+var nssProfiles = []string{
+	".pki/nssdb",
+	"snap/chromium/current/.pki/nssdb",
+	".mozilla/firefox/*",
+	"snap/firefox/common/.mozilla/firefox/*",
+	"Library/Application Support/Firefox/Profiles/*",
+}
+
+// nss is the set of NSS databases found in the user's home, managed with certutil.
+type nss struct {
+	root string
+	home string
+}
+
+func (nss) Name() string { return "Firefox/Chromium NSS databases" }
+
+func (n nss) Available() bool {
+	if _, err := exec.LookPath("certutil"); err != nil {
+		return false
+	}
+	return len(n.Databases()) > 0
+}
+
+// Databases returns the NSS databases, in the "sql:<dir>" or "dbm:<dir>" form certutil takes.
+func (n nss) Databases() []string {
+	var databases []string
+	for _, pattern := range nssProfiles {
+		dirs, _ := filepath.Glob(filepath.Join(n.root, n.home, pattern))
+		for _, dir := range dirs {
+			switch {
+			case exists(filepath.Join(dir, "cert9.db")):
+				databases = append(databases, "sql:"+dir)
+			case exists(filepath.Join(dir, "cert8.db")):
+				databases = append(databases, "dbm:"+dir)
+			}
+		}
+	}
+	return databases
+}
+
+// Installed reports whether every database holds the certificate.
+func (n nss) Installed(ca CA) bool {
+	databases := n.Databases()
+	for _, database := range databases {
+		if _, err := run(true, "certutil", "-L", "-d", database, "-n", ca.Name); err != nil {
+			return false
+		}
+	}
+	return len(databases) > 0
+}
+
+// Install adds the certificate as a trusted issuer of server certificates
+// ("C,,"). certutil runs as the user owning the databases, so that the files it
+// rewrites keep their owner.
+func (n nss) Install(ca CA) error {
+	for _, database := range n.Databases() {
+		if _, err := run(true, "certutil", "-A", "-d", database, "-t", "C,,", "-n", ca.Name, "-i", ca.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n nss) Uninstall(ca CA) error {
+	for _, database := range n.Databases() {
+		// certutil -D fails when the nickname is unknown
+		if _, err := run(true, "certutil", "-L", "-d", database, "-n", ca.Name); err != nil {
+			continue
+		}
+		if _, err := run(true, "certutil", "-D", "-d", database, "-n", ca.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exists reports whether the file exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,83 @@
+package trust
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// CA is the root certificate to trust.
+type CA struct {
+	Cert *x509.Certificate
+	File string // The PEM file holding it
+	Name string // The file name and nickname it is stored under, e.g. "localhost-root-ca"
+}
+
+// PEM returns the certificate in PEM form.
+func (ca CA) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})
+}
+
+// Store is a place an OS or a browser looks up trusted root certificates in.
+type Store interface {
+	Name() string
+	Available() bool // Whether the store exists on this machine
+	Installed(ca CA) bool
+	Install(ca CA) error
+	Uninstall(ca CA) error
+}
+
+// Config describes where the stores live.
+type Config struct {
+	Root string // Root directory the system paths are relative to, "/" on a live system
+	Home string // Home of the user whose browser databases are updated, relative to Root
+}
+
+// Stores returns every store the tool knows about; callers keep the available ones.
+func Stores(cfg Config) []Store {
+	stores := []Store{keychain{root: cfg.Root}}
+	for _, dir := range linuxDirs {
+		stores = append(stores, dirStore{linuxDir: dir, root: cfg.Root})
+	}
+	return append(stores, nss{root: cfg.Root, home: cfg.Home})
+}
+
+// AvailableStores returns the stores that exist on this machine.
+func AvailableStores(cfg Config) []Store {
+	var available []Store
+	for _, store := range Stores(cfg) {
+		if store.Available() {
+			available = append(available, store)
+		}
+	}
+	return available
+}
+
+// run runs a trust store command and returns its output. The error carries the
+// command and its output.
+func run(asOriginalUser bool, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	var err error
+	if asOriginalUser {
+		err = utils.RunAsOriginalUser(cmd)
+	} else {
+		err = cmd.Run()
+	}
+	if err != nil {
+		output := strings.TrimSpace(out.String())
+		if output == "" {
+			output = err.Error()
+		}
+		return out.String(), fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), output)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,178 @@
+package trust
+
+import (
+	"bytes"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liviu-hariton/localhost/internal/certs"
+	"github.com/liviu-hariton/localhost/internal/utils"
+)
+
+// testCA creates a certificate authority and its PEM file in a temporary directory.
+func testCA(t *testing.T) CA {
+	t.Helper()
+
+	cert, key, err := certs.NewCA("test Root CA", certs.DefaultOptions())
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "rootCA.crt")
+	if err := certs.Save(certFile, filepath.Join(dir, "rootCA.key"), cert, key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return CA{Cert: cert, File: certFile, Name: "localhost-root-ca"}
+}
+
+func TestDirStores(t *testing.T) {
+	ca := testCA(t)
+	utils.BackupDir = t.TempDir()
+
+	for _, dir := range linuxDirs {
+		t.Run(dir.name, func(t *testing.T) {
+			root := t.TempDir()
+			store := dirStore{linuxDir: dir, root: root}
+
+			if store.Available() {
+				t.Fatalf("Available without %s", dir.dir)
+			}
+
+			anchors := filepath.Join(root, dir.dir)
+			if err := os.MkdirAll(anchors, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if !store.Available() {
+				t.Fatalf("not Available with %s", dir.dir)
+			}
+
+			// An anchor installed by someone else, with the extension the store uses
+			foreign := filepath.Join(anchors, "corporate-root"+dir.ext)
+			foreignData := []byte("-----BEGIN CERTIFICATE-----\nforeign\n-----END CERTIFICATE-----\n")
+			if err := os.WriteFile(foreign, foreignData, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if store.Installed(ca) {
+				t.Errorf("Installed before Install")
+			}
+			if err := store.Install(ca); err != nil {
+				t.Fatalf("Install: %v", err)
+			}
+
+			want := filepath.Join(anchors, "localhost-root-ca"+dir.ext)
+			if store.Path(ca) != want {
+				t.Errorf("Path = %s, want %s", store.Path(ca), want)
+			}
+			data, err := os.ReadFile(want)
+			if err != nil {
+				t.Fatalf("anchor file: %v", err)
+			}
+			block, rest := pem.Decode(data)
+			if block == nil || block.Type != "CERTIFICATE" || !bytes.Equal(block.Bytes, ca.Cert.Raw) || len(bytes.TrimSpace(rest)) != 0 {
+				t.Errorf("anchor file does not hold exactly the CA certificate:\n%s", data)
+			}
+			info, err := os.Stat(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != 0644 {
+				t.Errorf("anchor mode = %o, want 644", mode)
+			}
+			if !store.Installed(ca) {
+				t.Errorf("not Installed after Install")
+			}
+
+			// Installing twice leaves a single anchor
+			if err := store.Install(ca); err != nil {
+				t.Fatalf("second Install: %v", err)
+			}
+			entries, err := os.ReadDir(anchors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 {
+				t.Errorf("anchor directory holds %d files, want 2", len(entries))
+			}
+
+			if err := store.Uninstall(ca); err != nil {
+				t.Fatalf("Uninstall: %v", err)
+			}
+			if _, err := os.Stat(want); !os.IsNotExist(err) {
+				t.Errorf("anchor file still exists after Uninstall")
+			}
+			if store.Installed(ca) {
+				t.Errorf("Installed after Uninstall")
+			}
+			if data, err := os.ReadFile(foreign); err != nil || !bytes.Equal(data, foreignData) {
+				t.Errorf("Uninstall touched a file the tool did not write")
+			}
+
+			// Uninstalling again is not an error
+			if err := store.Uninstall(ca); err != nil {
+				t.Errorf("second Uninstall: %v", err)
+			}
+		})
+	}
+}
+
+func TestAvailableStoresUnderRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc/pki/ca-trust/source/anchors"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The keychain is never used under another root, and the home holds no NSS database
+	stores := AvailableStores(Config{Root: root, Home: "/home/nobody"})
+	if len(stores) != 1 || stores[0].Name() != "Fedora/RHEL system store" {
+		var names []string
+		for _, store := range stores {
+			names = append(names, store.Name())
+		}
+		t.Errorf("AvailableStores = %v, want [Fedora/RHEL system store]", names)
+	}
+}
+
+func TestNSSDatabases(t *testing.T) {
+	root := t.TempDir()
+	home := "/home/dev"
+
+	for path, file := range map[string]string{
+		".pki/nssdb":                            "cert9.db",
+		".mozilla/firefox/abcd.default-release": "cert9.db",
+		".mozilla/firefox/old.default":          "cert8.db",
+		".mozilla/firefox/empty.profile":        "",
+	} {
+		dir := filepath.Join(root, home, path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if file != "" {
+			if err := os.WriteFile(filepath.Join(dir, file), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got := map[string]bool{}
+	for _, database := range (nss{root: root, home: home}).Databases() {
+		got[database] = true
+	}
+
+	want := []string{
+		"sql:" + filepath.Join(root, home, ".pki/nssdb"),
+		"sql:" + filepath.Join(root, home, ".mozilla/firefox/abcd.default-release"),
+		"dbm:" + filepath.Join(root, home, ".mozilla/firefox/old.default"),
+	}
+	if len(got) != len(want) {
+		t.Errorf("Databases = %v, want %v", got, want)
+	}
+	for _, database := range want {
+		if !got[database] {
+			t.Errorf("Databases is missing %s", database)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupDir is where previous versions of the files edited by the tool are kept.
+// It is set from the resolved paths at startup.
+var BackupDir string
+
+// MaxBackups is the number of backups kept per file, one per command that changed
+// it; older ones are rotated out.
+const MaxBackups = 10
+
+// backupTimestampFormat sorts lexically in chronological order.
+const backupTimestampFormat = "20060102-150405.000"
+
+// Backup is a saved copy of a file taken before it was overwritten.
+type Backup struct {
+	Path      string
+	Timestamp string
+	Time      time.Time
+	Size      int64
+}
+
+// backupDirFor returns the directory holding the backups of the given file.
+func backupDirFor(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %s", path, err.Error())
+	}
+
+	name := strings.ReplaceAll(strings.TrimPrefix(absPath, "/"), "/", "_")
+	return filepath.Join(BackupDir, name), nil
+}
+
+// CreateBackup copies the current content of path into a new timestamped backup
+// and rotates out the oldest backups beyond MaxBackups.
+func CreateBackup(path string) (*Backup, error) {
+	dir, err := backupDirFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory %s: %s", dir, err.Error())
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s for backup: %s", path, err.Error())
+	}
+	defer src.Close()
+
+	// Several writes can happen within the same millisecond; never overwrite an earlier backup
+	now := time.Now()
+	var dst *os.File
+	var timestamp, backupPath string
+	for {
+		timestamp = now.Format(backupTimestampFormat)
+		backupPath = filepath.Join(dir, timestamp)
+
+		dst, err = os.OpenFile(backupPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(err) {
+			now = now.Add(time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup of %s: %s", path, err.Error())
+		}
+		break
+	}
+
+	size, err := io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(backupPath)
+		return nil, fmt.Errorf("failed to write backup of %s: %s", path, err.Error())
+	}
+
+	if err := rotateBackups(path); err != nil {
+		return nil, err
+	}
+
+	return &Backup{Path: backupPath, Timestamp: timestamp, Time: now, Size: size}, nil
+}
+
+// ListBackups returns the backups of the given file, oldest first.
+func ListBackups(path string) ([]Backup, error) {
+	dir, err := backupDirFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory %s: %s", dir, err.Error())
+	}
+
+	var backups []Backup
+	for _, file := range files {
+		t, err := time.ParseInLocation(backupTimestampFormat, file.Name(), time.Local)
+		if err != nil || !file.Type().IsRegular() {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			Path:      filepath.Join(dir, file.Name()),
+			Timestamp: file.Name(),
+			Time:      t,
+			Size:      info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Timestamp < backups[j].Timestamp
+	})
+
+	return backups, nil
+}
+
+// FindBackup returns the backup of path taken at the given timestamp. A unique
+// prefix of the timestamp is accepted, and "latest" selects the newest backup.
+func FindBackup(path, timestamp string) (*Backup, error) {
+	backups, err := ListBackups(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups found for %s", path)
+	}
+
+	if timestamp == "latest" {
+		return &backups[len(backups)-1], nil
+	}
+
+	var matches []Backup
+	for _, backup := range backups {
+		if strings.HasPrefix(backup.Timestamp, timestamp) {
+			matches = append(matches, backup)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no backup of %s matches '%s'", path, timestamp)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("'%s' matches %d backups of %s, please be more specific", timestamp, len(matches), path)
+	}
+}
+
+// RestoreBackup rolls path back to the content of the given backup. The current
+// content is itself backed up first, so a restore can be undone.
+func RestoreBackup(path string, backup *Backup) error {
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %s", backup.Path, err.Error())
+	}
+
+	return WriteFileAtomic(path, data, 0644)
+}
+
+// rotateBackups removes the oldest backups of path beyond MaxBackups.
+func rotateBackups(path string) error {
+	backups, err := ListBackups(path)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > MaxBackups {
+		if err := os.Remove(backups[0].Path); err != nil {
+			return fmt.Errorf("failed to rotate backup %s: %s", backups[0].Path, err.Error())
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
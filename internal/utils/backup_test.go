@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateBackupRotates(t *testing.T) {
+	BackupDir = t.TempDir()
+	path := filepath.Join(t.TempDir(), "httpd.conf")
+
+	for i := 0; i < MaxBackups+3; i++ {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("version %d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateBackup(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != MaxBackups {
+		t.Fatalf("%d backups kept, want %d", len(backups), MaxBackups)
+	}
+
+	// The oldest ones were rotated out
+	for i, backup := range backups {
+		want := fmt.Sprintf("version %d\n", i+3)
+		if data, _ := os.ReadFile(backup.Path); string(data) != want {
+			t.Errorf("backup %d holds %q, want %q", i, data, want)
+		}
+	}
+}
+
+func TestFindBackup(t *testing.T) {
+	BackupDir = t.TempDir()
+	path := filepath.Join(t.TempDir(), "hosts")
+
+	if _, err := FindBackup(path, "latest"); err == nil {
+		t.Error("latest without backups: expected an error")
+	}
+
+	dir, err := backupDirFor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, timestamp := range []string{"20250101-100000.000", "20250102-090000.000", "20250102-093000.000"} {
+		if err := os.WriteFile(filepath.Join(dir, timestamp), []byte(timestamp), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Not a backup
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		timestamp string
+		want      string // Empty when an error is expected
+	}{
+		{"latest", "20250102-093000.000"},
+		{"20250101", "20250101-100000.000"},
+		{"20250102-0930", "20250102-093000.000"},
+		{"20250102-090000.000", "20250102-090000.000"},
+		{"20250102", ""}, // Ambiguous
+		{"2024", ""},
+		{"notes", ""},
+	} {
+		backup, err := FindBackup(path, tc.timestamp)
+		switch {
+		case tc.want == "" && err == nil:
+			t.Errorf("FindBackup(%s) = %s, expected an error", tc.timestamp, backup.Timestamp)
+		case tc.want != "" && err != nil:
+			t.Errorf("FindBackup(%s): %v", tc.timestamp, err)
+		case tc.want != "" && backup.Timestamp != tc.want:
+			t.Errorf("FindBackup(%s) = %s, want %s", tc.timestamp, backup.Timestamp, tc.want)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package utils
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // HasFlag checks if a specific flag is present in the command-line arguments
 func HasFlag(flag string) bool {
@@ -11,3 +17,52 @@ func HasFlag(flag string) bool {
 	}
 	return false
 }
+
+// ExtractFlagValue removes a global "--name value" or "--name=value" flag from the
+// arguments and returns its value along with the remaining arguments
+func ExtractFlagValue(args []string, name string) (string, []string) {
+	var value string
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--"+name || arg == "-"+name:
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--"+name+"="):
+			value = strings.TrimPrefix(arg, "--"+name+"=")
+		case strings.HasPrefix(arg, "-"+name+"="):
+			value = strings.TrimPrefix(arg, "-"+name+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	return value, rest
+}
+
+// StringList is a flag value that can be given several times, collecting every value
+type StringList []string
+
+// String returns the collected values, comma separated
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends a value each time the flag is given
+func (l *StringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// ParseDays parses a number of days such as "30" or "30d".
+func ParseDays(value string) (time.Duration, error) {
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "d"))
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid number of days '%s': expected e.g. 30 or 30d", value)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
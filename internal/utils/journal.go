@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// journalEntry is the state of a file before the current command first changed
+// it, or a directory the command created.
+type journalEntry struct {
+	path    string
+	existed bool
+	data    []byte
+	perm    os.FileMode
+	dir     bool
+}
+
+// journal lists the files changed by the current command, in the order they
+// were first changed.
+var journal []journalEntry
+
+// recordChange saves the state of the file before its first change and reports
+// whether this is the first change. info is the current state of the file, nil
+// when it does not exist yet.
+func recordChange(path string, info os.FileInfo) (bool, error) {
+	for _, entry := range journal {
+		if entry.path == path {
+			return false, nil
+		}
+	}
+
+	entry := journalEntry{path: path}
+	if info != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %s", path, err.Error())
+		}
+		entry.existed, entry.data, entry.perm = true, data, info.Mode().Perm()
+	}
+
+	journal = append(journal, entry)
+	return true, nil
+}
+
+// recordCreatedDirectory records a directory the current command created, which
+// RevertChanges removes again once it is empty.
+func recordCreatedDirectory(path string) {
+	for _, entry := range journal {
+		if entry.path == path {
+			return
+		}
+	}
+	journal = append(journal, journalEntry{path: path, dir: true})
+}
+
+// RecordFile records the state of a file the current command is about to write
+// by other means than WriteFileAtomic, so that RevertChanges can put it back.
+// Unlike WriteFileAtomic it keeps no backup, e.g. of a private key.
+func RecordFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, err = recordChange(path, info)
+	return err
+}
+
+// RemoveFile deletes a file, recording and backing it up first like WriteFileAtomic
+// does, so that RevertChanges can bring it back.
+func RemoveFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	first, err := recordChange(path, info)
+	if err != nil {
+		return err
+	}
+	if first {
+		if _, err := CreateBackup(path); err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(path)
+}
+
+// ChangedFiles returns the files and directories changed by the current command.
+func ChangedFiles() []string {
+	var files []string
+	for _, entry := range journal {
+		files = append(files, entry.path)
+	}
+	return files
+}
+
+// RevertChanges puts every file changed by the current command back the way
+// it was, deleting the files and directories the command created, and clears
+// the journal. It returns the reverted paths.
+func RevertChanges() ([]string, error) {
+	var reverted, failed []string
+
+	for i := len(journal) - 1; i >= 0; i-- {
+		entry := journal[i]
+
+		var err error
+		if entry.dir {
+			// A directory that is not empty holds files the command did not write
+			if err = os.Remove(entry.path); os.IsNotExist(err) {
+				err = nil
+			}
+		} else if entry.existed {
+			info, statErr := os.Stat(entry.path)
+			if statErr != nil {
+				info = nil
+			}
+			err = replaceFile(entry.path, entry.data, entry.perm, info)
+		} else if err = os.Remove(entry.path); os.IsNotExist(err) {
+			err = nil
+		}
+
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", entry.path, err.Error()))
+			continue
+		}
+		reverted = append(reverted, entry.path)
+	}
+
+	journal = nil
+
+	if len(failed) > 0 {
+		return reverted, fmt.Errorf("failed to revert %s", strings.Join(failed, ", "))
+	}
+	return reverted, nil
+}
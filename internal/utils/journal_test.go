@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRevertChanges(t *testing.T) {
+	journal = nil
+	BackupDir = t.TempDir()
+	dir := t.TempDir()
+
+	edited := filepath.Join(dir, "httpd.conf")
+	if err := os.WriteFile(edited, []byte("Listen 8080\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	removed := filepath.Join(dir, "myproject.local.conf")
+	if err := os.WriteFile(removed, []byte("<VirtualHost *:80>\n</VirtualHost>\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Written twice: the first state is the one restored
+	if err := WriteFileAtomic(edited, []byte("Listen 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(edited, []byte("Listen 443\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vhosts := filepath.Join(dir, "extra", "vhosts")
+	if err := CreateDirectory(vhosts); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(vhosts, "shop.local.conf")
+	if err := WriteFileAtomic(created, []byte("<VirtualHost *:80>\n</VirtualHost>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveFile(removed); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := RevertChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 5 {
+		t.Errorf("reverted %v, want 5 paths", reverted)
+	}
+
+	for path, want := range map[string]struct {
+		data string
+		perm os.FileMode
+	}{
+		edited:  {"Listen 8080\n", 0640},
+		removed: {"<VirtualHost *:80>\n</VirtualHost>\n", 0600},
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		info, _ := os.Stat(path)
+		if string(data) != want.data || info.Mode().Perm() != want.perm {
+			t.Errorf("%s = %q (%v), want %q (%v)", path, data, info.Mode().Perm(), want.data, want.perm)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra")); !os.IsNotExist(err) {
+		t.Errorf("the created directories are left behind (%v)", err)
+	}
+	if files := ChangedFiles(); len(files) != 0 {
+		t.Errorf("the journal still lists %v", files)
+	}
+}
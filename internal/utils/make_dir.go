@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 func CreateDirectory(path string) error {
@@ -11,10 +12,23 @@ func CreateDirectory(path string) error {
 		return nil
 	}
 
+	// The directories that do not exist yet, outermost first
+	var missing []string
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+	}
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return LogError(fmt.Sprintf("Creating directory %s", path), err)
 	}
 
+	// Recorded so that RevertChanges removes them, innermost first
+	for _, dir := range missing {
+		recordCreatedDirectory(dir)
+	}
+
 	fmt.Printf("✔ Created directory: %s\n", path)
 	return nil
 }
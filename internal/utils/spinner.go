@@ -8,7 +8,6 @@ import (
 // Spinner displays a rotating spinner until the provided function completes.
 func Spinner(message string, fn func() error) error {
 	stop := make(chan bool)
-	errCh := make(chan error)
 
 	// Start the spinner in a goroutine
 	go func() {
@@ -31,15 +30,11 @@ func Spinner(message string, fn func() error) error {
 
 	// Stop the spinner and clear the line
 	stop <- true
-	fmt.Printf("\r%s ✔\n", message)
-
-	// Pass along any error from the function
 	if err != nil {
-		errCh <- err
-		close(errCh)
+		fmt.Printf("\r%s ✘\n", message)
 		return err
 	}
 
-	close(errCh)
+	fmt.Printf("\r%s ✔\n", message)
 	return nil
 }
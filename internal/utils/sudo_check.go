@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/user"
+	"sort"
 	"strings"
 )
 
@@ -18,7 +20,7 @@ func RelaunchWithSudo() error {
 	// Relaunch the program with sudo
 	LogWarning("Insufficient permissions. Relaunching with sudo...")
 
-	cmd := exec.Command("sudo", append([]string{os.Args[0]}, os.Args[1:]...)...)
+	cmd := exec.Command("sudo", sudoArgs(os.Args, os.Environ())...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -34,6 +36,23 @@ func RelaunchWithSudo() error {
 	return nil // This line will never be reached
 }
 
+// sudoArgs returns the sudo arguments relaunching the program, asking sudo to
+// keep the LOCALHOST_* overrides it would otherwise strip from the environment.
+func sudoArgs(args, environ []string) []string {
+	var keep []string
+	for _, variable := range environ {
+		if name, _, _ := strings.Cut(variable, "="); strings.HasPrefix(name, "LOCALHOST_") {
+			keep = append(keep, name)
+		}
+	}
+
+	if len(keep) == 0 {
+		return args
+	}
+	sort.Strings(keep)
+	return append([]string{"--preserve-env=" + strings.Join(keep, ",")}, args...)
+}
+
 // GetOriginalUser returns the original username when running with sudo, or the current user otherwise.
 func GetOriginalUser() string {
 	// When running with sudo, SUDO_USER contains the original username
@@ -44,6 +63,26 @@ func GetOriginalUser() string {
 	return os.Getenv("USER")
 }
 
+// GetOriginalHome returns the home directory of the original user when running with sudo,
+// or the current user's home directory otherwise.
+func GetOriginalHome() string {
+	if os.Getenv("SUDO_USER") == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home
+		}
+	}
+
+	if sudoHome := os.Getenv("SUDO_HOME"); sudoHome != "" {
+		return sudoHome
+	}
+
+	// Fallback: look the user up, then construct the macOS home path
+	if u, err := user.Lookup(GetOriginalUser()); err == nil && u.HomeDir != "" {
+		return u.HomeDir
+	}
+	return fmt.Sprintf("/Users/%s", GetOriginalUser())
+}
+
 // RunAsOriginalUser runs a command as the original user (not root).
 // This is necessary for Homebrew commands which refuse to run as root.
 func RunAsOriginalUser(cmd *exec.Cmd) error {
@@ -59,11 +98,7 @@ func RunAsOriginalUser(cmd *exec.Cmd) error {
 	}
 
 	// Get the original user's home directory
-	originalHome := os.Getenv("SUDO_HOME")
-	if originalHome == "" {
-		// Fallback: construct home path (works on macOS/Linux)
-		originalHome = fmt.Sprintf("/Users/%s", originalUser)
-	}
+	originalHome := GetOriginalHome()
 
 	// Build the command to run as the original user
 	// We need to use sudo -u to switch to the original user
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSudoArgsPreservesOverrides(t *testing.T) {
+	args := []string{"/usr/local/bin/localhost", "create", "myproject.local"}
+
+	got := sudoArgs(args, []string{"HOME=/Users/dev", "PATH=/usr/bin"})
+	if strings.Join(got, " ") != strings.Join(args, " ") {
+		t.Errorf("without overrides: sudo %v", got)
+	}
+
+	got = sudoArgs(args, []string{"LOCALHOST_VHOSTS_DIR=/tmp/vhosts", "HOME=/Users/dev", "LOCALHOST_CONFIG=/tmp/config"})
+	want := "--preserve-env=LOCALHOST_CONFIG,LOCALHOST_VHOSTS_DIR /usr/local/bin/localhost create myproject.local"
+	if strings.Join(got, " ") != want {
+		t.Errorf("sudo %v, want sudo %s", got, want)
+	}
+}
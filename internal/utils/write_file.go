@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// WriteFileAtomic replaces the file at path with data without ever leaving a
+// half-written file behind. The content the file had before the command first
+// changed it, if any, is saved as a timestamped backup, once per command; the
+// new content is written to a temporary file in the same directory, flushed to
+// disk and renamed over the original.
+//
+// The state of the file before the command first wrote it is recorded in the
+// journal, so that RevertChanges can undo the whole command.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to inspect %s: %s", path, err.Error())
+	}
+	if err != nil {
+		info = nil
+	}
+
+	first, err := recordChange(path, info)
+	if err != nil {
+		return err
+	}
+
+	// Later writes of the same command would only save its own intermediate states
+	if info != nil && first {
+		if _, err := CreateBackup(path); err != nil {
+			return err
+		}
+	}
+
+	return replaceFile(path, data, perm, info)
+}
+
+// replaceFile atomically writes data to path. When the file existed, info is its
+// previous state, whose permissions and owner are kept.
+func replaceFile(path string, data []byte, perm os.FileMode, info os.FileInfo) error {
+	if info != nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("permission denied: you must run this program with elevated permissions (e.g., using sudo)")
+		}
+		return fmt.Errorf("failed to create temporary file for %s: %s", path, err.Error())
+	}
+	tmpPath := tmp.Name()
+
+	// Make sure the temporary file never outlives a failed write
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %s", path, err.Error())
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %s", path, err.Error())
+	}
+	if info != nil {
+		// Keep the original owner, e.g. the Homebrew user for httpd.conf
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			if err := tmp.Chown(int(stat.Uid), int(stat.Gid)); err != nil && !os.IsPermission(err) {
+				return fmt.Errorf("failed to set ownership on %s: %s", path, err.Error())
+			}
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush %s to disk: %s", path, err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file for %s: %s", path, err.Error())
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %s", path, err.Error())
+	}
+	committed = true
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// WriteLinesAtomic writes the lines to path, each terminated by a newline, using WriteFileAtomic.
+func WriteLinesAtomic(path string, lines []string, perm os.FileMode) error {
+	var content strings.Builder
+	for _, line := range lines {
+		content.WriteString(line + "\n")
+	}
+
+	return WriteFileAtomic(path, []byte(content.String()), perm)
+}
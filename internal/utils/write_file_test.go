@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	journal = nil
+	BackupDir = t.TempDir()
+	dir := t.TempDir()
+	t.Cleanup(func() { journal = nil })
+
+	created := filepath.Join(dir, "myproject.local.conf")
+	if err := WriteFileAtomic(created, []byte("<VirtualHost *:80>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("created file: %v, %v", info, err)
+	}
+
+	// An existing file keeps its mode
+	existing := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(existing, []byte("127.0.0.1 localhost\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(existing, []byte("127.0.0.1 myproject.local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(existing)
+	info, _ := os.Stat(existing)
+	if string(data) != "127.0.0.1 myproject.local\n" || info.Mode().Perm() != 0600 {
+		t.Errorf("hosts = %q (%v)", data, info.Mode().Perm())
+	}
+
+	// No temporary file is left next to the files
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d entries, want 2", len(entries))
+	}
+}
+
+func TestWriteFileAtomicBacksUpOncePerCommand(t *testing.T) {
+	journal = nil
+	BackupDir = t.TempDir()
+	t.Cleanup(func() { journal = nil })
+
+	conf := filepath.Join(t.TempDir(), "httpd.conf")
+	if err := os.WriteFile(conf, []byte("Listen 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// As create does: the modules, the include and the SSL settings in turn
+	for _, content := range []string{"Listen 80\n", "Listen 80\nInclude vhosts/*.conf\n", "Listen 80\nListen 443\nInclude vhosts/*.conf\n"} {
+		if err := WriteFileAtomic(conf, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := ListBackups(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("%d backups, want 1", len(backups))
+	}
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != "Listen 8080\n" {
+		t.Errorf("the backup holds %q, want the content before the command", data)
+	}
+
+	// The next command backs it up again
+	journal = nil
+	if err := WriteFileAtomic(conf, []byte("Listen 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := ListBackups(conf); len(backups) != 2 {
+		t.Errorf("%d backups after a second command, want 2", len(backups))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/liviu-hariton/localhost/internal/commands"
+	"github.com/liviu-hariton/localhost/internal/paths"
 	"github.com/liviu-hariton/localhost/internal/utils"
 )
 
@@ -27,22 +28,42 @@ func main() {
 		}
 	}
 
-	if len(os.Args) < 2 {
+	// Resolve the file system layout and the settings once, before any command runs
+	root, args := utils.ExtractFlagValue(os.Args[1:], "root")
+	if err := paths.Init(root); err != nil {
+		utils.LogError("Loading the configuration", err)
+		os.Exit(1)
+	}
+	utils.BackupDir = paths.Get().BackupDir
+
+	if len(args) < 1 {
 		utils.LogWarning("No command provided. Use 'help' for usage information.")
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "create":
-		commands.CreateCommand(os.Args[2:])
+		commands.CreateCommand(args[1:])
 	case "list":
-		commands.ListCommand(os.Args[2:])
+		commands.ListCommand(args[1:])
 	case "delete":
-		commands.DeleteCommand(os.Args[2:])
+		commands.DeleteCommand(args[1:])
+	case "doctor":
+		commands.DoctorCommand(args[1:])
+	case "status":
+		commands.StatusCommand(args[1:])
+	case "dns":
+		commands.DNSCommand(args[1:])
+	case "ca":
+		commands.CACommand(args[1:])
+	case "certs":
+		commands.CertsCommand(args[1:])
+	case "restore":
+		commands.RestoreCommand(args[1:])
 	case "help":
-		commands.HelpCommand(os.Args[2:])
+		commands.HelpCommand(args[1:])
 	default:
-		utils.LogWarning(fmt.Sprintf("Unknown command '%s'. Use 'help' for usage information.\n", os.Args[1]))
+		utils.LogWarning(fmt.Sprintf("Unknown command '%s'. Use 'help' for usage information.\n", args[0]))
 		os.Exit(1)
 	}
 }